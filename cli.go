@@ -0,0 +1,72 @@
+// cli.go
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kdev1966/go-auth-api/passwords"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// runCommand exécute une commande d'administration au lieu de démarrer le serveur :
+//
+//	go-auth-api rotate-keys [HS256|RS256|ES256|EdDSA]
+//	go-auth-api create-admin <username> <email>
+//	go-auth-api create-admin --promote <email>
+func runCommand(args []string) {
+	switch args[0] {
+	case "rotate-keys":
+		algorithm := ""
+		if len(args) > 1 {
+			algorithm = args[1]
+		}
+		key, err := utils.RotateSigningKey(algorithm)
+		if err != nil {
+			log.Fatal("Erreur lors de la rotation de la clé de signature:", err)
+		}
+		log.Printf("Nouvelle clé de signature active: kid=%s, algorithme=%s", key.ID, key.Algorithm)
+	case "create-admin":
+		if len(args) == 3 && args[1] == "--promote" {
+			user, err := utils.PromoteAdmin(args[2])
+			if err != nil {
+				log.Fatal("Erreur lors de la promotion de l'administrateur:", err)
+			}
+			log.Printf("Compte existant promu administrateur : %s (id=%d)", user.Email, user.ID)
+			return
+		}
+		if len(args) < 3 {
+			log.Fatal("Usage: create-admin <username> <email> (mot de passe via ADMIN_PASSWORD ou l'entrée standard), ou create-admin --promote <email> pour un compte existant")
+		}
+		password := os.Getenv("ADMIN_PASSWORD")
+		if password == "" {
+			fmt.Print("Mot de passe : ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				log.Fatal("Erreur lors de la lecture du mot de passe:", err)
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+		user, err := utils.CreateAdmin(args[1], args[2], password)
+		var policyErr *passwords.PolicyError
+		if errors.As(err, &policyErr) {
+			for _, v := range policyErr.Violations {
+				log.Println("-", v.Message)
+			}
+		}
+		if errors.Is(err, utils.ErrAdminEmailTaken) {
+			log.Fatalf("Erreur lors de la création de l'administrateur: %v (pour le promouvoir : create-admin --promote %s)", err, args[2])
+		}
+		if err != nil {
+			log.Fatal("Erreur lors de la création de l'administrateur:", err)
+		}
+		log.Printf("Administrateur créé : %s (id=%d)", user.Email, user.ID)
+	default:
+		log.Fatalf("Commande inconnue: %s", args[0])
+	}
+}
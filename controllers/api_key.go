@@ -0,0 +1,88 @@
+// controllers/api_key.go
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// CreateMyAPIKey crée une clé d'API pour l'utilisateur connecté.
+// La clé en clair n'est renvoyée qu'une seule fois, dans cette réponse.
+func CreateMyAPIKey(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input struct {
+		Name      string     `json:"name" binding:"required"`
+		Scopes    []string   `json:"scopes"`     // optionnel : vide = tous les droits de l'utilisateur
+		ExpiresAt *time.Time `json:"expires_at"` // optionnel : RFC 3339
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range input.Scopes {
+		if !utils.ValidAPIKeyScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scope inconnu : " + scope, "allowed_scopes": utils.APIKeyScopes})
+			return
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La date d'expiration doit être dans le futur"})
+		return
+	}
+
+	key, rawKey, err := utils.CreateAPIKey(userID, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la clé d'API"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Clé d'API créée, conservez-la : elle ne sera plus affichée",
+		"key":     rawKey,
+		"api_key": key,
+	})
+	utils.LogActivity(userID, "api_key_created", "Création de la clé d'API "+key.Prefix+" ("+key.Name+")")
+}
+
+// GetMyAPIKeys liste les clés d'API de l'utilisateur connecté (sans leur valeur).
+func GetMyAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+	if err := config.DB.Where("user_id = ?", c.GetUint("user_id")).Order("created_at desc").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les clés d'API"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// DeleteMyAPIKey révoque une clé d'API de l'utilisateur connecté.
+func DeleteMyAPIKey(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var key models.APIKey
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).First(&key).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Clé d'API non trouvée"})
+		return
+	}
+	if err := config.DB.Model(&key).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Clé d'API révoquée avec succès"})
+	utils.LogActivity(userID, "api_key_revoked", "Révocation de la clé d'API "+key.Prefix+" ("+key.Name+")")
+}
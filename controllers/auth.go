@@ -2,57 +2,30 @@
 package controllers
 
 import (
+	"log"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/kdev1966/go-auth-api/config"
 	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/passwords"
 	"github.com/kdev1966/go-auth-api/utils"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// Claims étend les réclamations JWT en ajoutant l'ID de l'utilisateur.
-type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
-	jwt.StandardClaims
-}
-
-// generateJWT génère un token JWT en incluant l'ID de l'utilisateur.
-func GenerateToken(user models.User) (string, error) {
-	mySigningKey := []byte(os.Getenv("JWT_SECRET"))
-
-	claims := Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(), // Expiration 24h
-			Issuer:    "go-auth-api",
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString(mySigningKey)
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
-}
-
 // Register crée un nouvel utilisateur.
 func Register(c *gin.Context) {
 	var input struct {
 		Username string `json:"username" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required"`
-		Role     string `json:"role"` // Optionnel, par défaut "user"
+		Role     string `json:"role"` // refusé s'il diffère de "user" : voir PUT /api/users/:id/role
+
+		InvitationToken string `json:"invitation_token"` // optionnel : lien d'invitation à une organisation
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -60,8 +33,32 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Le rôle n'est jamais choisi par le client : tout nouveau compte est un simple utilisateur
+	if input.Role != "" && input.Role != utils.RoleUser {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Le rôle ne peut pas être choisi à l'inscription"})
+		return
+	}
+
+	// Inscription depuis une invitation : elle doit être valide et adressée à cet email
+	var invitation *models.Invitation
+	if input.InvitationToken != "" {
+		var err error
+		if invitation, err = utils.FindInvitationByToken(input.InvitationToken); err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		if !strings.EqualFold(invitation.Email, input.Email) {
+			respondInvitationError(c, utils.ErrInvitationEmailMismatch)
+			return
+		}
+	}
+
+	if respondPasswordPolicyError(c, passwords.Validate(input.Password, input.Username, input.Email)) {
+		return
+	}
+
 	// Hachage du mot de passe
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := passwords.Hash(input.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du hachage du mot de passe"})
 		return
@@ -70,8 +67,13 @@ func Register(c *gin.Context) {
 	user := models.User{
 		Username: input.Username,
 		Email:    input.Email,
-		Password: string(hashedPassword),
-		Role:     input.Role,
+		Password: hashedPassword,
+		Role:     utils.RoleUser,
+	}
+	if invitation != nil {
+		// Le lien reçu par email prouve l'adresse
+		now := time.Now()
+		user.EmailVerifiedAt = &now
 	}
 
 	if err := config.DB.Create(&user).Error; err != nil {
@@ -79,14 +81,34 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Utilisateur créé avec succès"})
+	// Le rôle principal donne les permissions correspondantes
+	if _, err := utils.AssignRole(user.ID, user.Role); err != nil {
+		log.Println("Erreur lors de l'attribution du rôle:", err)
+	}
+
+	if invitation != nil {
+		acceptRegistrationInvitations(&user, invitation)
+		c.JSON(http.StatusCreated, gin.H{"message": "Utilisateur créé avec succès, invitation acceptée", "organization": invitation.Organization})
+		return
+	}
+
+	// Lien de vérification de l'adresse ; un échec d'envoi n'annule pas l'inscription
+	// (l'utilisateur peut redemander un lien via /api/verify-email/resend). Sans lien
+	// d'invitation, les invitations en attente pour cette adresse ne sont pas acceptées
+	// automatiquement : chacune l'est avec son lien, via /api/invitations/accept.
+	if err := utils.SendVerificationEmail(&user); err != nil {
+		log.Println("Erreur lors de l'envoi de l'email de vérification:", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Utilisateur créé avec succès, un email de vérification a été envoyé"})
 }
 
 // Login authentifie l'utilisateur et génère un token JWT.
 func Login(c *gin.Context) {
 	var input struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name"` // optionnel : nom affiché dans la liste des sessions
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -94,32 +116,129 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+
 	var user models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non trouvé"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+	err := config.DB.Where("email = ? AND deleted_at IS NULL", input.Email).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var account *models.User
+	if err == nil {
+		account = &user
+	}
+
+	if wait := utils.LoginRetryAfter(account, input.Email, ip); wait > 0 {
+		tooManyLoginAttempts(c, wait)
+		return
+	}
+
+	// Un email inconnu prend le même temps qu'un mauvais mot de passe
+	if account == nil {
+		passwords.VerifyDummy(input.Password)
+		failLogin(c, nil, input.Email, ip)
 		return
 	}
 
 	// Comparaison des mots de passe
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Mot de passe incorrect"})
+	ok, needsRehash, err := passwords.Verify(input.Password, user.Password)
+	if err != nil {
+		log.Println("Erreur lors de la vérification du mot de passe:", err)
+	}
+	if !ok {
+		failLogin(c, &user, input.Email, ip)
+		return
+	}
+
+	// Ancien hachage (bcrypt, paramètres dépassés, pepper absent) : remplacé maintenant
+	// que le mot de passe en clair est connu
+	if needsRehash {
+		if hashedPassword, err := passwords.Hash(input.Password); err == nil {
+			if err := config.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+				log.Println("Erreur lors de la mise à jour du hachage du mot de passe:", err)
+			}
+		}
+	}
+
+	if !requireVerifiedEmail(c, &user) {
+		return
+	}
+
+	// Double authentification : les tokens ne sont émis qu'après /api/login/mfa. Les
+	// compteurs d'échecs du compte ne sont remis à zéro qu'une fois le second facteur validé
+	if user.TOTPEnabled {
+		mfaToken, err := utils.IssueMFAPendingToken(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Code de double authentification requis",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
+
+	if err := utils.ResetFailedLogins(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithLoginTokens(c, &user, input.DeviceName, []string{utils.AMRPassword}, "Utilisateur connecté avec succès")
+}
+
+// respondPasswordPolicyError renvoie les violations de la politique de mot de passe,
+// champ par champ. Retourne false si err n'est pas une erreur de politique.
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	policyErr, ok := err.(*passwords.PolicyError)
+	if !ok {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      "Le mot de passe ne respecte pas la politique de sécurité",
+		"violations": policyErr.Violations,
+	})
+	return true
+}
+
+// failLogin enregistre un échec de connexion et renvoie l'erreur générique,
+// identique que l'email soit inconnu ou le mot de passe incorrect.
+func failLogin(c *gin.Context, user *models.User, email, ip string) {
+	if err := utils.RecordFailedLogin(user, email, ip); err != nil {
+		log.Println("Erreur lors de l'enregistrement de l'échec de connexion:", err)
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou mot de passe incorrect"})
+}
+
+// tooManyLoginAttempts refuse une tentative pendant un délai ou un verrouillage.
+func tooManyLoginAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Trop de tentatives de connexion, réessayez plus tard",
+		"retry_after": seconds,
+	})
+}
+
+// respondWithLoginTokens ouvre une session pour un utilisateur authentifié et renvoie
+// le couple access/refresh token. Toutes les méthodes de connexion passent par ici,
+// afin que les clients reçoivent la même réponse quelle que soit la méthode utilisée.
+// authMethods sont les méthodes utilisées, reprises dans le claim "amr" des id_tokens.
+func respondWithLoginTokens(c *gin.Context, user *models.User, deviceName string, authMethods []string, logDetails string) {
+	// Nouvelle session pour cet appareil, avec son refresh token
+	session, refreshToken, err := utils.CreateSession(user.ID, deviceName, authMethods, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la session"})
 		return
 	}
 
 	// Générer le token JWT
-	accessToken, err := utils.GenerateToken(user.ID, 15*time.Minute)
+	accessToken, err := utils.IssueAccessToken(user, session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du token"})
 		return
 	}
-	refreshToken, err := utils.GenerateRefreshToken(user.ID, 7*24*time.Hour)
-
-	// Sauvegarder le refresh token côté DB
-	config.DB.Model(&user).Update("refresh_token", refreshToken)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Connexion réussie",
@@ -127,10 +246,11 @@ func Login(c *gin.Context) {
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
 	})
-	utils.LogActivity(user.ID, "login", "Utilisateur connecté avec succès")
+	utils.LogActivity(user.ID, "login", logDetails)
 }
 
-// refresh 	token
+// RefreshToken échange un refresh token contre un nouveau couple access/refresh token.
+// Le refresh token présenté est retiré (rotation) ; sa réutilisation révoque toute la famille.
 func RefreshToken(c *gin.Context) {
 	var body struct {
 		RefreshToken string `json:"refresh_token"`
@@ -140,34 +260,90 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Vérifie le token
-	token, err := jwt.Parse(body.RefreshToken, func(t *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token invalide"})
+	// Vérifie le token et le remplace par un nouveau au sein de la session
+	session, newRefreshToken, err := utils.RefreshSession(body.RefreshToken, "", c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch err {
+		case utils.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token déjà utilisé, session révoquée"})
+		case utils.ErrInvalidRefreshToken:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token invalide"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de renouveler le refresh token"})
+		}
 		return
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	userID := uint(claims["user_id"].(float64))
-
-	// Compare avec celui en base
+	// Recharge l'utilisateur : le nouveau token reflète son rôle actuel
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil || user.RefreshToken != body.RefreshToken {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token non reconnu"})
+	if err := config.DB.First(&user, session.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non trouvé"})
 		return
 	}
 
 	// Génère un nouveau access token
-	newAccessToken, err := utils.GenerateToken(userID, 15*time.Minute)
+	newAccessToken, err := utils.IssueAccessToken(&user, session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de générer un nouveau token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"access_token": newAccessToken,
+		"access_token":  newAccessToken,
+		"refresh_token": newRefreshToken,
 	})
 }
+
+// Logout révoque le token d'accès courant, ferme la session associée ainsi que celle du refresh token fourni (optionnel).
+func Logout(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// Le corps est optionnel : on ignore une éventuelle erreur de binding
+	_ = c.ShouldBindJSON(&body)
+
+	// Révocation du token d'accès utilisé pour cette requête
+	if err := utils.RevokeToken(c.GetString("jti"), userID, time.Unix(c.GetInt64("token_exp"), 0)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la révocation du token"})
+		return
+	}
+
+	// Fermeture de la session courante
+	if sessionID := c.GetUint("session_id"); sessionID != 0 {
+		var session models.Session
+		if err := config.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err == nil {
+			if err := utils.RevokeSession(&session); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la fermeture de la session"})
+				return
+			}
+		}
+	}
+
+	// Révocation de la famille du refresh token s'il appartient bien à l'utilisateur
+	if body.RefreshToken != "" {
+		if claims, err := utils.ParseRefreshToken(body.RefreshToken); err == nil && claims.UserID() == userID {
+			if err := utils.RevokeRefreshTokenFamily(claims.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la révocation du token"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion réussie"})
+	utils.LogActivity(userID, "logout", "Utilisateur déconnecté")
+}
+
+// LogoutAll révoque tous les tokens de l'utilisateur, sur tous ses appareils.
+func LogoutAll(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if err := utils.RevokeAllUserTokens(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la révocation des tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion de tous les appareils réussie"})
+	utils.LogActivity(userID, "logout_all", "Utilisateur déconnecté de tous les appareils")
+}
@@ -0,0 +1,183 @@
+// controllers/device.go
+
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// deviceVerificationURI retourne la page du frontend où l'utilisateur saisit le user_code
+// (OAUTH_DEVICE_VERIFICATION_URL). L'API n'en sert aucune : GET /api/device et
+// POST /api/device/approve attendent un appel JSON authentifié par cette page.
+func deviceVerificationURI() string {
+	return os.Getenv("OAUTH_DEVICE_VERIFICATION_URL")
+}
+
+// DeviceAuthorization ouvre une demande d'autorisation pour un appareil sans navigateur
+// (RFC 8628, section 3.1). L'appareil affiche le user_code et la page où le saisir, puis
+// interroge /oauth/token avec le device_code. Le grant est désactivé tant que
+// OAUTH_DEVICE_VERIFICATION_URL n'est pas configurée.
+func DeviceAuthorization(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	verificationURI := deviceVerificationURI()
+	if verificationURI == "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnsupportedGrantType, Description: "grant device_code désactivé (OAUTH_DEVICE_VERIFICATION_URL non configurée)"})
+		return
+	}
+
+	client, err := authenticateTokenClient(c)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, err := utils.ResolveOAuthScope(client, c.PostForm("scope"))
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	if utils.HasScope(scope, utils.ScopeOpenID) && !utils.OIDCAvailable() {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidScope, Description: utils.ErrOIDCUnavailable.Error()})
+		return
+	}
+
+	record, deviceCode, err := utils.CreateDeviceCode(client, scope)
+	if err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	userCode := utils.FormatUserCode(record.UserCode)
+	c.JSON(http.StatusOK, gin.H{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": oauthRedirect(verificationURI, "", url.Values{"user_code": {userCode}}),
+		"expires_in":                int(utils.DeviceCodeTTL.Seconds()),
+		"interval":                  record.PollInterval,
+	})
+}
+
+// tokenFromDeviceCode échange un device_code approuvé par l'utilisateur contre une session
+// du client (RFC 8628, section 3.4).
+func tokenFromDeviceCode(c *gin.Context, client *models.OAuthClient) {
+	deviceCode := c.PostForm("device_code")
+	if deviceCode == "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "device_code manquant"})
+		return
+	}
+	record, err := utils.PollDeviceCode(client, deviceCode)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	user, err := loadOAuthUser(record.UserID)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	session, refreshToken, err := utils.CreateOAuthSession(user.ID, client, record.Scope, record.AuthTime, record.AuthMethods, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondOAuthTokens(c, user, session, refreshToken, "")
+	utils.LogActivity(user.ID, "oauth_token_issued", "Tokens délivrés à l'appareil du client OAuth "+client.Name)
+}
+
+// findDeviceRequest charge la demande en attente désignée par le user_code et son client.
+func findDeviceRequest(c *gin.Context, userCode string) (*models.OAuthDeviceCode, *models.OAuthClient, bool) {
+	record, err := utils.FindPendingDeviceCode(userCode)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code invalide, expiré ou déjà utilisé"})
+		return nil, nil, false
+	}
+	client, err := utils.FindOAuthClient(record.ClientID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code invalide, expiré ou déjà utilisé"})
+		return nil, nil, false
+	}
+	return record, client, true
+}
+
+// GetDeviceRequest retourne le client et les scopes d'une demande d'appareil, à présenter à
+// l'utilisateur avant qu'il ne l'approuve.
+func GetDeviceRequest(c *gin.Context) {
+	record, client, ok := findDeviceRequest(c, c.Query("user_code"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"client":     gin.H{"client_id": client.ClientID, "name": client.Name},
+		"scopes":     record.Scope,
+		"expires_at": record.ExpiresAt,
+	})
+}
+
+// respondDeviceAnswerError répond à l'échec de l'enregistrement d'une réponse : la demande a
+// pu expirer ou recevoir une autre réponse entre-temps.
+func respondDeviceAnswerError(c *gin.Context, err error) {
+	if err == utils.ErrInvalidUserCode {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code invalide, expiré ou déjà utilisé"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ApproveDevice enregistre la réponse de l'utilisateur connecté à une demande d'appareil.
+// L'appareil reçoit ses tokens à sa prochaine interrogation de /oauth/token.
+func ApproveDevice(c *gin.Context) {
+	var input struct {
+		UserCode string `json:"user_code" binding:"required"`
+		Approve  bool   `json:"approve"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	record, client, ok := findDeviceRequest(c, input.UserCode)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !input.Approve {
+		if err := utils.AnswerDeviceCode(record, userID, false, time.Time{}, ""); err != nil {
+			respondDeviceAnswerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Accès refusé à l'appareil"})
+		utils.LogActivity(userID, "oauth_denied", "Autorisation refusée à l'appareil du client OAuth "+client.Name)
+		return
+	}
+
+	// La session de l'appareil reprend l'authentification de la session courante
+	authTime, authMethods := time.Now(), ""
+	var session models.Session
+	if err := config.DB.First(&session, c.GetUint("session_id")).Error; err == nil {
+		authTime = utils.SessionAuthTime(&session)
+		authMethods = session.AuthMethods
+	}
+	if err := utils.SaveOAuthConsent(userID, client.ClientID, record.Scope); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := utils.AnswerDeviceCode(record, userID, true, authTime, authMethods); err != nil {
+		respondDeviceAnswerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Appareil autorisé, il va recevoir ses accès"})
+	utils.LogActivity(userID, "oauth_authorized", "Autorisation accordée à l'appareil du client OAuth "+client.Name+" ("+record.Scope+")")
+}
@@ -0,0 +1,71 @@
+// controllers/email_verification.go
+
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// VerifyEmail confirme l'adresse email à partir du token reçu par email.
+func VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := utils.VerifyEmail(input.Token)
+	if err != nil {
+		if err == utils.ErrInvalidVerificationToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token de vérification invalide ou expiré"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Adresse email vérifiée avec succès"})
+	utils.LogActivity(user.ID, "email_verified", "Adresse email vérifiée : "+user.Email)
+}
+
+// ResendVerificationEmail renvoie un lien de vérification. La réponse est la même
+// que le compte existe ou non, pour ne pas révéler les adresses inscrites.
+func ResendVerificationEmail(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err == nil && user.EmailVerifiedAt == nil {
+		if err := utils.SendVerificationEmail(&user); err != nil {
+			log.Println("Erreur lors de l'envoi de l'email de vérification:", err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Si un compte non vérifié existe pour cette adresse, un email a été envoyé"})
+}
+
+// requireVerifiedEmail refuse la connexion d'un compte non vérifié lorsque
+// REQUIRE_EMAIL_VERIFICATION est activé. Retourne false si la réponse a été écrite.
+func requireVerifiedEmail(c *gin.Context, user *models.User) bool {
+	if user.EmailVerifiedAt != nil || !utils.RequireEmailVerification() {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":                       "Adresse email non vérifiée",
+		"email_verification_required": true,
+	})
+	return false
+}
@@ -0,0 +1,268 @@
+// controllers/group.go
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/gorm"
+)
+
+// findRoles charge les rôles demandés et signale les noms inconnus.
+func findRoles(c *gin.Context, names []string) ([]models.Role, bool) {
+	roles := []models.Role{}
+	if len(names) == 0 {
+		return roles, true
+	}
+	if err := config.DB.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(roles) != len(names) {
+		known := map[string]bool{}
+		for _, r := range roles {
+			known[r.Name] = true
+		}
+		for _, name := range names {
+			if !known[name] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle inconnu : " + name})
+				return nil, false
+			}
+		}
+	}
+	return roles, true
+}
+
+// findGroup charge le groupe désigné par le paramètre :id, avec ses rôles.
+func findGroup(c *gin.Context) (*models.Group, bool) {
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var group models.Group
+	if err := config.DB.Preload("Roles").First(&group, groupID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Groupe non trouvé"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return &group, true
+}
+
+// checkGroupParent vérifie que le parent demandé existe et ne crée pas de cycle.
+func checkGroupParent(c *gin.Context, groupID, parentID uint) bool {
+	var count int64
+	config.DB.Model(&models.Group{}).Where("id = ?", parentID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Groupe parent introuvable"})
+		return false
+	}
+	if err := utils.CheckGroupParent(groupID, parentID); err != nil {
+		if err == utils.ErrGroupCycle {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Un groupe ne peut pas être son propre ancêtre"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return false
+	}
+	return true
+}
+
+// GetGroups liste les groupes et leurs rôles.
+func GetGroups(c *gin.Context) {
+	var groups []models.Group
+	if err := config.DB.Preload("Roles").Order("name").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les groupes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": groups})
+}
+
+// GetGroup retourne un groupe avec ses rôles, ses membres directs et ses sous-groupes.
+func GetGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+	if err := config.DB.Model(group).Association("Members").Find(&group.Members); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	subgroups := []models.Group{}
+	if err := config.DB.Where("parent_id = ?", group.ID).Order("name").Find(&subgroups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"group": group, "subgroups": subgroups})
+}
+
+// CreateGroup crée un groupe, éventuellement sous un groupe parent, avec une liste de rôles.
+func CreateGroup(c *gin.Context) {
+	var input struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		ParentID    *uint    `json:"parent_id"`
+		Roles       []string `json:"roles"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.ParentID != nil && !checkGroupParent(c, 0, *input.ParentID) {
+		return
+	}
+	roles, ok := findRoles(c, input.Roles)
+	if !ok {
+		return
+	}
+
+	group := models.Group{Name: input.Name, Description: input.Description, ParentID: input.ParentID, Roles: roles}
+	if err := config.DB.Create(&group).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Un groupe porte déjà ce nom"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Groupe créé avec succès", "group": group})
+	utils.LogActivity(c.GetUint("user_id"), "group_created", "Création du groupe "+group.Name)
+}
+
+// UpdateGroup modifie la description, le parent et/ou les rôles d'un groupe.
+func UpdateGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Description *string  `json:"description"`
+		ParentID    *uint    `json:"parent_id"` // 0 détache le groupe de son parent
+		Roles       []string `json:"roles"`     // remplace la liste complète si présent
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.ParentID != nil {
+		if *input.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if !checkGroupParent(c, group.ID, *input.ParentID) {
+				return
+			}
+			updates["parent_id"] = *input.ParentID
+		}
+	}
+	var roles []models.Role
+	if input.Roles != nil {
+		if roles, ok = findRoles(c, input.Roles); !ok {
+			return
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(group).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if input.Roles != nil {
+			return tx.Model(group).Association("Roles").Replace(roles)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Groupe mis à jour avec succès", "group": group})
+	utils.LogActivity(c.GetUint("user_id"), "group_updated", "Modification du groupe "+group.Name)
+}
+
+// DeleteGroup supprime un groupe ; ses sous-groupes sont rattachés à son parent.
+func DeleteGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := utils.DeleteGroup(group); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Groupe supprimé avec succès"})
+	utils.LogActivity(c.GetUint("user_id"), "group_deleted", "Suppression du groupe "+group.Name)
+}
+
+// AddGroupMember ajoute un utilisateur à un groupe.
+func AddGroupMember(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, input.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if err := config.DB.Model(group).Association("Members").Append(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Membre ajouté au groupe avec succès"})
+	actorID := c.GetUint("user_id")
+	utils.LogActivityBy(actorID, user.ID, "group_member_added", fmt.Sprintf("Ajout au groupe %s par l'utilisateur %d", group.Name, actorID))
+}
+
+// RemoveGroupMember retire un utilisateur d'un groupe.
+func RemoveGroupMember(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	result := config.DB.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ?", group.ID, userID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "L'utilisateur n'est pas membre de ce groupe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Membre retiré du groupe avec succès"})
+	actorID := c.GetUint("user_id")
+	utils.LogActivityBy(actorID, uint(userID), "group_member_removed", fmt.Sprintf("Retrait du groupe %s par l'utilisateur %d", group.Name, actorID))
+}
@@ -0,0 +1,182 @@
+// controllers/introspection.go
+
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/middleware"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// tokenCaller est l'appelant authentifié de /oauth/introspect ou /oauth/revoke : un client
+// (OAuth ou de service) ou le propriétaire d'une clé d'API.
+type tokenCaller struct {
+	clientID     string
+	confidential bool // client OAuth confidentiel ou client de service
+	service      bool
+	scopes       []string // scopes du client de service ou de la clé d'API (nil : clé sans restriction)
+	userID       uint     // propriétaire de la clé d'API
+}
+
+// hasScope indique si l'appelant a reçu scope. Une clé d'API sans scope les a tous.
+func (caller *tokenCaller) hasScope(scope string) bool {
+	if caller.userID != 0 && caller.scopes == nil {
+		return true
+	}
+	for _, s := range caller.scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateTokenCaller authentifie l'appelant : clé d'API (X-API-Key ou Authorization:
+// Bearer pat_...), sinon identifiants du client comme pour /oauth/token.
+func authenticateTokenCaller(c *gin.Context) (*tokenCaller, error) {
+	rawKey := c.GetHeader("X-API-Key")
+	if header := c.GetHeader("Authorization"); rawKey == "" && strings.HasPrefix(header, "Bearer ") && utils.IsAPIKey(header[7:]) {
+		rawKey = header[7:]
+	}
+	if rawKey != "" {
+		key, user, err := utils.AuthenticateAPIKey(rawKey)
+		if err != nil {
+			return nil, &utils.OAuthError{Code: utils.OAuthInvalidClient, Description: "clé d'API invalide, expirée ou révoquée"}
+		}
+		// Les permissions du propriétaire sont lues comme pour une requête authentifiée
+		c.Set("user_id", user.ID)
+		return &tokenCaller{userID: user.ID, scopes: utils.APIKeyScopeList(key)}, nil
+	}
+
+	clientID, secret, err := tokenClientCredentials(c)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(clientID, utils.ServiceClientIDPrefix) {
+		client, err := utils.AuthenticateServiceClient(clientID, secret)
+		if err != nil {
+			return nil, err
+		}
+		return &tokenCaller{clientID: client.ClientID, confidential: true, service: true, scopes: strings.Fields(client.Scopes)}, nil
+	}
+	client, err := utils.AuthenticateOAuthClient(clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenCaller{clientID: client.ClientID, confidential: client.Confidential}, nil
+}
+
+// owns indique si le token a été délivré à l'appelant : à son client, ou au propriétaire de
+// sa clé d'API.
+func (caller *tokenCaller) owns(info *utils.TokenInfo) bool {
+	if caller.clientID != "" {
+		return info.ClientID == caller.clientID
+	}
+	return info.UserID == caller.userID
+}
+
+// tokenRequestParam lit le token à traiter, obligatoire.
+func tokenRequestParam(c *gin.Context) (string, bool) {
+	token := c.PostForm("token")
+	if token == "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "token manquant"})
+		return "", false
+	}
+	return token, true
+}
+
+// IntrospectToken indique si un token est actif et le décrit (RFC 7662). Une clé d'API
+// restreinte doit avoir le scope tokens:introspect. Les clients de service ayant ce scope et
+// les clés d'API dont le propriétaire a la permission tokens:inspect voient tous les tokens ;
+// les autres appelants ne voient que les tokens qui leur ont été délivrés.
+func IntrospectToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	caller, err := authenticateTokenCaller(c)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !caller.confidential && caller.clientID != "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnauthorizedClient, Description: "un client public ne peut pas introspecter de token"})
+		return
+	}
+	if caller.userID != 0 && !caller.hasScope(utils.ScopeTokensIntrospect) {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnauthorizedClient, Description: "clé d'API sans le scope " + utils.ScopeTokensIntrospect})
+		return
+	}
+	token, ok := tokenRequestParam(c)
+	if !ok {
+		return
+	}
+
+	// Un token invalide, expiré, révoqué ou invisible pour l'appelant est simplement inactif
+	info, err := utils.InspectToken(token, c.PostForm("token_type_hint"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.TokenIntrospection{Active: false})
+		return
+	}
+	inspectAll := (caller.service && caller.hasScope(utils.ScopeTokensIntrospect)) || (caller.userID != 0 && middleware.GlobalPermissions(c)[utils.PermTokensInspect])
+	if !inspectAll && !caller.owns(info) {
+		c.JSON(http.StatusOK, utils.TokenIntrospection{Active: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.Introspect(info))
+}
+
+// RevokeToken révoque un token d'accès ou un refresh token (RFC 7009). Un client ne révoque
+// que les tokens qui lui ont été délivrés ; une clé d'API, sans restriction ou avec le scope
+// tokens:revoke, ceux de son propriétaire, ou ceux de tous les utilisateurs avec la
+// permission sessions:admin. Un token invalide ou déjà révoqué n'est pas une erreur.
+func RevokeToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	caller, err := authenticateTokenCaller(c)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	if caller.userID != 0 && !caller.hasScope(utils.ScopeTokensRevoke) {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnauthorizedClient, Description: "clé d'API sans le scope " + utils.ScopeTokensRevoke})
+		return
+	}
+	token, ok := tokenRequestParam(c)
+	if !ok {
+		return
+	}
+
+	info, err := utils.InspectToken(token, c.PostForm("token_type_hint"))
+	if err != nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	revokeAll := caller.userID != 0 && middleware.GlobalPermissions(c)[utils.PermSessionsAdmin]
+	if !revokeAll && !caller.owns(info) {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnauthorizedClient, Description: "ce token n'a pas été délivré à l'appelant"})
+		return
+	}
+
+	if err := utils.RevokeInspectedToken(info); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": "Erreur lors de la révocation du token"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+	if info.UserID != 0 {
+		kind := "token d'accès"
+		if info.Refresh != nil {
+			kind = "refresh token"
+		}
+		if caller.clientID != "" {
+			utils.LogActivity(info.UserID, "token_revoked", "Révocation d'un "+kind+" par le client "+caller.clientID)
+		} else {
+			utils.LogActivityBy(caller.userID, info.UserID, "token_revoked", "Révocation d'un "+kind+" avec une clé d'API")
+		}
+	}
+}
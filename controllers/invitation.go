@@ -0,0 +1,223 @@
+// controllers/invitation.go
+
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// findInvitation charge l'invitation désignée par le paramètre :invitation_id dans l'organisation.
+func findInvitation(c *gin.Context, orgID uint) (*models.Invitation, bool) {
+	invitationID, err := strconv.Atoi(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var invitation models.Invitation
+	if err := config.DB.Where("id = ? AND organization_id = ?", invitationID, orgID).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation non trouvée"})
+		return nil, false
+	}
+	return &invitation, true
+}
+
+// respondInvitationError traduit les erreurs liées aux invitations.
+func respondInvitationError(c *gin.Context, err error) {
+	switch err {
+	case utils.ErrInvalidInvitation:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation invalide, expirée ou déjà utilisée"})
+	case utils.ErrInvitationEmailMismatch:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cette invitation a été envoyée à une autre adresse email"})
+	case utils.ErrInvitationPending:
+		c.JSON(http.StatusConflict, gin.H{"error": "Une invitation est déjà en attente pour cette adresse"})
+	default:
+		respondMembershipError(c, err)
+	}
+}
+
+// CreateInvitation invite une adresse email à rejoindre l'organisation et lui envoie le lien.
+// Requiert orgs:manage dans l'organisation.
+func CreateInvitation(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role"` // par défaut "user"
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID := c.GetUint("user_id")
+	invitation, err := utils.CreateInvitation(org.ID, input.Email, input.Role, actorID)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	if err := utils.SendInvitationEmail(invitation, org); err != nil {
+		log.Println("Erreur lors de l'envoi de l'invitation:", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Invitation envoyée", "invitation": invitation})
+	utils.LogActivity(actorID, "org_invitation_created", fmt.Sprintf("Invitation de %s dans l'organisation %s (id=%d) avec le rôle %s", invitation.Email, org.Slug, org.ID, invitation.Role))
+}
+
+// GetInvitations liste les invitations d'une organisation, par défaut celles en attente
+// (paramètre status : pending, accepted, declined, revoked ou all). Requiert orgs:manage.
+func GetInvitations(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	query := config.DB.Where("organization_id = ?", org.ID).Order("created_at desc")
+	switch status := c.DefaultQuery("status", models.InvitationPending); status {
+	case "all":
+	case models.InvitationPending:
+		query = query.Where("status = ? AND expires_at > ?", status, time.Now())
+	case models.InvitationAccepted, models.InvitationDeclined, models.InvitationRevoked:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Statut inconnu : " + status})
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+// ResendInvitation renvoie le lien d'une invitation en attente et prolonge sa validité,
+// y compris si elle avait expiré. Requiert orgs:manage.
+func ResendInvitation(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+	invitation, ok := findInvitation(c, org.ID)
+	if !ok {
+		return
+	}
+
+	if err := utils.SendInvitationEmail(invitation, org); err != nil {
+		if err == utils.ErrInvalidInvitation {
+			respondInvitationError(c, err)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'envoi de l'invitation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation renvoyée"})
+	utils.LogActivity(c.GetUint("user_id"), "org_invitation_resent", fmt.Sprintf("Renvoi de l'invitation de %s dans l'organisation %s (id=%d)", invitation.Email, org.Slug, org.ID))
+}
+
+// RevokeInvitation annule une invitation en attente. Requiert orgs:manage.
+func RevokeInvitation(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+	invitation, ok := findInvitation(c, org.ID)
+	if !ok {
+		return
+	}
+
+	if err := utils.RevokeInvitation(invitation); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation annulée"})
+	utils.LogActivity(c.GetUint("user_id"), "org_invitation_revoked", fmt.Sprintf("Annulation de l'invitation de %s dans l'organisation %s (id=%d)", invitation.Email, org.Slug, org.ID))
+}
+
+// AcceptInvitation fait entrer l'utilisateur connecté dans l'organisation de l'invitation.
+// L'invitation doit avoir été envoyée à l'adresse de son compte.
+func AcceptInvitation(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := utils.FindInvitationByToken(input.Token)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if err := utils.AcceptInvitation(invitation, &user); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation acceptée", "organization": invitation.Organization})
+	utils.LogActivity(user.ID, "org_invitation_accepted", fmt.Sprintf("Entrée dans l'organisation %s (id=%d) avec le rôle %s", invitation.Organization.Slug, invitation.OrganizationID, invitation.Role))
+}
+
+// DeclineInvitation refuse une invitation. Le lien suffit : aucun compte n'est nécessaire.
+func DeclineInvitation(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := utils.FindInvitationByToken(input.Token)
+	if err == nil {
+		err = utils.DeclineInvitation(invitation)
+	}
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation refusée"})
+	utils.LogActivity(invitation.InvitedByID, "org_invitation_declined", fmt.Sprintf("Invitation de %s dans l'organisation %s (id=%d) refusée", invitation.Email, invitation.Organization.Slug, invitation.OrganizationID))
+}
+
+// acceptRegistrationInvitations rattache un compte créé avec un lien d'invitation à ses
+// organisations : celle du lien, puis les autres invitations en attente pour son adresse,
+// prouvée par ce lien. C'est le seul chemin d'acceptation automatique : un compte inscrit
+// sans lien accepte ses invitations via POST /api/invitations/accept. Les erreurs
+// n'annulent pas l'inscription.
+func acceptRegistrationInvitations(user *models.User, invitation *models.Invitation) {
+	if err := utils.AcceptInvitation(invitation, user); err != nil {
+		log.Println("Erreur lors de l'acceptation de l'invitation:", err)
+	} else {
+		utils.LogActivity(user.ID, "org_invitation_accepted", fmt.Sprintf("Entrée dans l'organisation %s (id=%d) avec le rôle %s", invitation.Organization.Slug, invitation.OrganizationID, invitation.Role))
+	}
+
+	accepted, err := utils.AcceptPendingInvitations(user)
+	if err != nil {
+		log.Println("Erreur lors de l'acceptation des invitations:", err)
+	}
+	for _, invitation := range accepted {
+		utils.LogActivity(user.ID, "org_invitation_accepted", fmt.Sprintf("Entrée dans l'organisation id=%d avec le rôle %s", invitation.OrganizationID, invitation.Role))
+	}
+}
@@ -0,0 +1,56 @@
+// controllers/keys.go
+
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// JWKS expose les clés publiques de vérification des tokens (RFC 7517).
+// Les services tiers peuvent ainsi vérifier les tokens sans pouvoir en émettre.
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.PublicJWKS()})
+}
+
+// GetSigningKeys liste les clés du trousseau (sans leur partie privée).
+// Requiert la permission keys:manage.
+func GetSigningKeys(c *gin.Context) {
+	var keys []models.SigningKey
+	if err := config.DB.Order("created_at desc").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les clés"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// RotateSigningKey génère une nouvelle clé de signature active.
+// Les tokens déjà émis restent valides jusqu'à leur expiration.
+// Requiert la permission keys:manage.
+func RotateSigningKey(c *gin.Context) {
+	var input struct {
+		Algorithm string `json:"algorithm" binding:"omitempty,oneof=HS256 RS256 ES256 EdDSA"` // optionnel : algorithme de la clé active
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := utils.RotateSigningKey(input.Algorithm)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la rotation de la clé"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Nouvelle clé de signature active",
+		"kid":       key.ID,
+		"algorithm": key.Algorithm,
+	})
+	utils.LogActivity(c.GetUint("user_id"), "rotate_signing_key", "Rotation de la clé de signature, nouveau kid "+key.ID)
+}
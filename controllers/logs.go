@@ -7,12 +7,25 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/middleware"
 	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
 )
 
+// GetActivityLogs retourne le journal d'activité : celui de tous les utilisateurs avec
+// la permission globale logs:read, celui des membres de l'organisation active avec la
+// permission donnée par un rôle de membre, sinon uniquement celui de l'utilisateur connecté.
 func GetActivityLogs(c *gin.Context) {
 	var logs []models.ActivityLog
-	if err := config.DB.Order("created_at desc").Find(&logs).Error; err != nil {
+	query := config.DB.Order("created_at desc")
+	switch {
+	case middleware.GlobalPermissions(c)[utils.PermLogsRead]:
+	case middleware.HasPermission(c, utils.PermLogsRead):
+		query = query.Where("user_id IN (?)", utils.OrgMemberIDs(c.GetUint("org_id")))
+	default:
+		query = query.Where("user_id = ?", c.GetUint("user_id"))
+	}
+	if err := query.Find(&logs).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les logs"})
 		return
 	}
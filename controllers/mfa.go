@@ -0,0 +1,262 @@
+// controllers/mfa.go
+
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/passwords"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// EnrollTOTP génère un secret TOTP pour l'utilisateur connecté.
+// La double authentification n'est active qu'après confirmation d'un premier code.
+func EnrollTOTP(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La double authentification est déjà activée"})
+		return
+	}
+
+	key, err := utils.GenerateTOTPKey(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du secret"})
+		return
+	}
+	qrCode, err := utils.TOTPQRCode(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du QR code"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    key.Secret(),
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      key.Secret(),
+		"otpauth_url": key.URL(),
+		"qr_code":     qrCode, // data:image/png;base64,...
+	})
+}
+
+// ConfirmTOTP active la double authentification après vérification d'un code
+// et retourne les codes de secours, affichés une seule fois.
+func ConfirmTOTP(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La double authentification est déjà activée"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Aucun enrôlement en cours"})
+		return
+	}
+
+	if !utils.ValidateTOTP(&user, input.Code) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code invalide"})
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération des codes de secours"})
+		return
+	}
+	if err := config.DB.Model(&user).Update("totp_enabled", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Double authentification activée",
+		"recovery_codes": codes,
+	})
+	utils.LogActivity(user.ID, "2fa_enabled", "Activation de la double authentification TOTP")
+}
+
+// DisableTOTP désactive la double authentification.
+// Le mot de passe et un code (TOTP ou de secours) sont exigés.
+func DisableTOTP(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La double authentification n'est pas activée"})
+		return
+	}
+
+	if ok, _, _ := passwords.Verify(input.Password, user.Password); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Mot de passe incorrect"})
+		return
+	}
+	if ok, _ := utils.VerifySecondFactor(&user, input.Code); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code invalide"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   false,
+		"totp_secret":    "",
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	config.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Double authentification désactivée"})
+	utils.LogActivity(user.ID, "2fa_disabled", "Désactivation de la double authentification TOTP")
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours de l'utilisateur connecté.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La double authentification n'est pas activée"})
+		return
+	}
+	if !utils.ValidateTOTP(&user, input.Code) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code invalide"})
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération des codes de secours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	utils.LogActivity(user.ID, "2fa_recovery_codes", "Régénération des codes de secours")
+}
+
+// LoginMFA échange le token "mfa_pending" obtenu au login et un code TOTP
+// (ou un code de secours) contre le couple access/refresh token.
+func LoginMFA(c *gin.Context) {
+	var input struct {
+		MFAToken   string `json:"mfa_token" binding:"required"`
+		Code       string `json:"code" binding:"required"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ParseClaims(input.MFAToken, utils.TokenTypeMFAPending)
+	if err != nil || utils.IsTokenRevoked(claims.ID, claims.UserID(), claims.IssuedAtUnix()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token MFA invalide ou expiré"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID()).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token MFA invalide ou expiré"})
+		return
+	}
+
+	// Les échecs du second facteur comptent dans le verrouillage du compte, comme ceux du mot
+	// de passe : un nouveau login ne donne pas de nouvelles tentatives
+	ip := c.ClientIP()
+	if wait := utils.LoginRetryAfter(&user, user.Email, ip); wait > 0 {
+		tooManyLoginAttempts(c, wait)
+		return
+	}
+
+	// La tentative est comptée avant la vérification du code ; au-delà de la limite, le token
+	// est révoqué et il faut recommencer le login
+	if err := utils.ReserveMFAAttempt(claims); err != nil {
+		if err != utils.ErrTooManyMFAAttempts {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		utils.RevokeToken(claims.ID, user.ID, claims.ExpiresAt.Time)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Trop de codes invalides, reconnectez-vous"})
+		return
+	}
+
+	ok, method := utils.VerifySecondFactor(&user, input.Code)
+	if !ok {
+		utils.LogActivity(user.ID, "2fa_failed", "Code de double authentification invalide")
+		if err := utils.RecordFailedLogin(&user, user.Email, ip); err != nil {
+			log.Println("Erreur lors de l'enregistrement de l'échec de connexion:", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code invalide"})
+		return
+	}
+
+	// Le token MFA est à usage unique
+	if err := utils.RevokeToken(claims.ID, user.ID, time.Now().Add(utils.MFAPendingTokenTTL)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la révocation du token"})
+		return
+	}
+	if err := utils.ResetFailedLogins(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	details := "Utilisateur connecté avec succès (TOTP)"
+	authMethods := []string{utils.AMRPassword, utils.AMROTP, utils.AMRMultiFactor}
+	if method == "recovery_code" {
+		details = "Utilisateur connecté avec succès (code de secours)"
+		authMethods = []string{utils.AMRPassword, utils.AMRMultiFactor}
+	}
+	respondWithLoginTokens(c, &user, input.DeviceName, authMethods, details)
+}
@@ -0,0 +1,379 @@
+// controllers/oauth.go
+
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// authorizeRequest regroupe les paramètres de /oauth/authorize (RFC 6749 section 4.1.1,
+// RFC 7636 et OpenID Connect Core section 3.1.2.1).
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	Prompt              string `form:"prompt" json:"prompt"` // "none" ou "consent"
+}
+
+// respondOAuthError renvoie une erreur au format OAuth : {"error": code, "error_description": ...}.
+func respondOAuthError(c *gin.Context, status int, err error) {
+	var oauthErr *utils.OAuthError
+	if !errors.As(err, &oauthErr) {
+		log.Println("Erreur OAuth:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": "Erreur interne"})
+		return
+	}
+	if oauthErr.Code == utils.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
+
+// oauthRedirect construit l'URL de retour vers le client avec les paramètres donnés et le state.
+func oauthRedirect(redirectURI, state string, params url.Values) string {
+	if state != "" {
+		params.Set("state", state)
+	}
+	u, _ := url.Parse(redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redirectOAuthError renvoie au client une erreur d'autorisation via son redirect_uri.
+func redirectOAuthError(c *gin.Context, req *authorizeRequest, err error) {
+	var oauthErr *utils.OAuthError
+	if !errors.As(err, &oauthErr) {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+	params := url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}}
+	c.JSON(http.StatusOK, gin.H{"redirect_to": oauthRedirect(req.RedirectURI, req.State, params)})
+}
+
+// validateAuthorizeRequest vérifie une demande d'autorisation. Tant que le client et son
+// redirect_uri ne sont pas établis, l'erreur est renvoyée directement ; ensuite elle est
+// transmise au client via son redirect_uri.
+func validateAuthorizeRequest(c *gin.Context, req *authorizeRequest) (*models.OAuthClient, string, bool) {
+	client, err := utils.FindOAuthClient(req.ClientID)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return nil, "", false
+	}
+	if req.RedirectURI == "" || !utils.OAuthRedirectAllowed(client, req.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": "redirect_uri non enregistrée pour ce client"})
+		return nil, "", false
+	}
+
+	if req.ResponseType != "code" {
+		redirectOAuthError(c, req, &utils.OAuthError{Code: utils.OAuthUnsupportedResponseType, Description: "seul response_type=code est accepté"})
+		return nil, "", false
+	}
+	if req.CodeChallengeMethod != "S256" || !utils.ValidPKCEChallenge(req.CodeChallenge) {
+		redirectOAuthError(c, req, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "PKCE requis : code_challenge avec code_challenge_method=S256"})
+		return nil, "", false
+	}
+	scope, err := utils.ResolveOAuthScope(client, req.Scope)
+	if err != nil {
+		redirectOAuthError(c, req, err)
+		return nil, "", false
+	}
+	if utils.HasScope(scope, utils.ScopeOpenID) && !utils.OIDCAvailable() {
+		redirectOAuthError(c, req, &utils.OAuthError{Code: utils.OAuthInvalidScope, Description: utils.ErrOIDCUnavailable.Error()})
+		return nil, "", false
+	}
+	return client, scope, true
+}
+
+// issueAuthorizationCode émet le code d'autorisation et renvoie l'URL de retour vers le client.
+// Le code retient l'authentification de la session courante, reprise dans les id_tokens.
+func issueAuthorizationCode(c *gin.Context, req *authorizeRequest, client *models.OAuthClient, scope string) {
+	userID := c.GetUint("user_id")
+	record := models.OAuthAuthorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		AuthTime:      time.Now(),
+	}
+	var session models.Session
+	if err := config.DB.First(&session, c.GetUint("session_id")).Error; err == nil {
+		record.AuthTime = utils.SessionAuthTime(&session)
+		record.AuthMethods = session.AuthMethods
+	}
+
+	code, err := utils.CreateAuthorizationCode(&record)
+	if err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"redirect_to": oauthRedirect(req.RedirectURI, req.State, url.Values{"code": {code}})})
+	utils.LogActivity(userID, "oauth_authorized", "Autorisation accordée au client OAuth "+client.Name+" ("+scope+")")
+}
+
+// Authorize examine une demande d'autorisation pour l'utilisateur connecté. L'écran de
+// connexion/consentement étant servi par le frontend, la réponse est en JSON : soit
+// "redirect_to" (accord déjà donné, ou erreur à transmettre au client), soit
+// "consent_required" avec le client et les scopes à présenter à l'utilisateur.
+func Authorize(c *gin.Context) {
+	var req authorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": err.Error()})
+		return
+	}
+	client, scope, ok := validateAuthorizeRequest(c, &req)
+	if !ok {
+		return
+	}
+
+	consented := utils.HasOAuthConsent(c.GetUint("user_id"), client.ClientID, scope)
+	switch {
+	case consented && req.Prompt != "consent":
+		issueAuthorizationCode(c, &req, client, scope)
+		return
+	case !consented && req.Prompt == "none":
+		redirectOAuthError(c, &req, &utils.OAuthError{Code: utils.OAuthConsentRequired, Description: "l'accord de l'utilisateur est requis"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consent_required": true,
+		"client":           gin.H{"client_id": client.ClientID, "name": client.Name},
+		"scopes":           scope,
+	})
+}
+
+// ApproveAuthorization enregistre la réponse de l'utilisateur à l'écran de consentement.
+// Mêmes paramètres que Authorize, plus "approve".
+func ApproveAuthorization(c *gin.Context) {
+	var input struct {
+		authorizeRequest
+		Approve bool `form:"approve" json:"approve"`
+	}
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": err.Error()})
+		return
+	}
+	req := &input.authorizeRequest
+	client, scope, ok := validateAuthorizeRequest(c, req)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !input.Approve {
+		redirectOAuthError(c, req, &utils.OAuthError{Code: utils.OAuthAccessDenied, Description: "l'utilisateur a refusé l'autorisation"})
+		utils.LogActivity(userID, "oauth_denied", "Autorisation refusée au client OAuth "+client.Name)
+		return
+	}
+
+	if err := utils.SaveOAuthConsent(userID, client.ClientID, scope); err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+	issueAuthorizationCode(c, req, client, scope)
+}
+
+// tokenClientCredentials lit les identifiants du client qui appelle /oauth/token : en-tête
+// Authorization Basic, ou client_id (et client_secret) dans le formulaire.
+func tokenClientCredentials(c *gin.Context) (string, string, error) {
+	clientID, secret, hasBasic := c.Request.BasicAuth()
+	if !hasBasic {
+		return c.PostForm("client_id"), c.PostForm("client_secret"), nil
+	}
+
+	// RFC 6749 section 2.3.1 : identifiants encodés en application/x-www-form-urlencoded
+	var err error
+	if clientID, err = url.QueryUnescape(clientID); err != nil {
+		return "", "", &utils.OAuthError{Code: utils.OAuthInvalidClient, Description: "en-tête Authorization invalide"}
+	}
+	if secret, err = url.QueryUnescape(secret); err != nil {
+		return "", "", &utils.OAuthError{Code: utils.OAuthInvalidClient, Description: "en-tête Authorization invalide"}
+	}
+	if formID := c.PostForm("client_id"); formID != "" && formID != clientID {
+		return "", "", &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "client_id différent de celui de l'en-tête Authorization"}
+	}
+	return clientID, secret, nil
+}
+
+// authenticateTokenClient authentifie le client OAuth qui appelle /oauth/token.
+func authenticateTokenClient(c *gin.Context) (*models.OAuthClient, error) {
+	clientID, secret, err := tokenClientCredentials(c)
+	if err != nil {
+		return nil, err
+	}
+	return utils.AuthenticateOAuthClient(clientID, secret)
+}
+
+// Token délivre les tokens d'un client OAuth (RFC 6749 section 3.2). Grants acceptés :
+// authorization_code (PKCE obligatoire), device_code et refresh_token pour les applications
+// agissant pour un utilisateur, client_credentials pour les clients de service.
+func Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if c.PostForm("grant_type") == "client_credentials" {
+		tokenFromClientCredentials(c)
+		return
+	}
+
+	client, err := authenticateTokenClient(c)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		tokenFromAuthorizationCode(c, client)
+	case "refresh_token":
+		tokenFromRefreshToken(c, client)
+	case utils.OAuthDeviceCodeGrantType:
+		tokenFromDeviceCode(c, client)
+	case "":
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "grant_type manquant"})
+	default:
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthUnsupportedGrantType, Description: "grant_type non pris en charge"})
+	}
+}
+
+// tokenFromClientCredentials délivre un token de service, sans utilisateur ni refresh token
+// (RFC 6749 section 4.4).
+func tokenFromClientCredentials(c *gin.Context) {
+	clientID, secret, err := tokenClientCredentials(c)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	client, err := utils.AuthenticateServiceClient(clientID, secret)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	accessToken, scope, err := utils.IssueServiceToken(client, c.PostForm("scope"))
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.ServiceTokenTTL.Seconds()),
+		"scope":        scope,
+	})
+}
+
+// loadOAuthUser charge l'utilisateur pour lequel un client demande des tokens.
+func loadOAuthUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil || user.DeletedAt != nil {
+		return nil, &utils.OAuthError{Code: utils.OAuthInvalidGrant, Description: "compte utilisateur introuvable ou supprimé"}
+	}
+	return &user, nil
+}
+
+// tokenFromAuthorizationCode échange un code d'autorisation contre une session du client.
+func tokenFromAuthorizationCode(c *gin.Context, client *models.OAuthClient) {
+	code := c.PostForm("code")
+	if code == "" || c.PostForm("code_verifier") == "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "code et code_verifier sont requis"})
+		return
+	}
+	record, err := utils.RedeemAuthorizationCode(client, code, c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	user, err := loadOAuthUser(record.UserID)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	session, refreshToken, err := utils.CreateOAuthSession(user.ID, client, record.Scope, record.AuthTime, record.AuthMethods, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := utils.AttachAuthorizationCodeSession(record, session.ID); err != nil {
+		log.Println("Erreur lors du rattachement de la session au code d'autorisation:", err)
+	}
+
+	respondOAuthTokens(c, user, session, refreshToken, record.Nonce)
+	utils.LogActivity(user.ID, "oauth_token_issued", "Tokens délivrés au client OAuth "+client.Name)
+}
+
+// tokenFromRefreshToken fait tourner le refresh token d'une session ouverte par ce client.
+func tokenFromRefreshToken(c *gin.Context, client *models.OAuthClient) {
+	refreshToken := c.PostForm("refresh_token")
+	if refreshToken == "" {
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidRequest, Description: "refresh_token manquant"})
+		return
+	}
+	session, newRefreshToken, err := utils.RefreshSession(refreshToken, client.ClientID, c.Request.UserAgent(), c.ClientIP())
+	switch err {
+	case nil:
+	case utils.ErrRefreshTokenReused:
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidGrant, Description: "refresh token déjà utilisé, session révoquée"})
+		return
+	case utils.ErrInvalidRefreshToken:
+		respondOAuthError(c, http.StatusBadRequest, &utils.OAuthError{Code: utils.OAuthInvalidGrant, Description: "refresh token invalide"})
+		return
+	default:
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err := loadOAuthUser(session.UserID)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, err)
+		return
+	}
+	respondOAuthTokens(c, user, session, newRefreshToken, "")
+}
+
+// respondOAuthTokens renvoie la réponse de /oauth/token (RFC 6749 section 5.1), avec un
+// id_token si le scope openid a été accordé.
+func respondOAuthTokens(c *gin.Context, user *models.User, session *models.Session, refreshToken, nonce string) {
+	accessToken, err := utils.IssueAccessToken(user, session)
+	if err != nil {
+		respondOAuthError(c, http.StatusInternalServerError, err)
+		return
+	}
+	response := gin.H{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"scope":         session.Scope,
+	}
+	if utils.HasScope(session.Scope, utils.ScopeOpenID) {
+		idToken, err := utils.IssueIDToken(user, session, nonce)
+		if err != nil {
+			respondOAuthError(c, http.StatusInternalServerError, err)
+			return
+		}
+		response["id_token"] = idToken
+	}
+	c.JSON(http.StatusOK, response)
+}
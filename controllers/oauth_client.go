@@ -0,0 +1,247 @@
+// controllers/oauth_client.go
+
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// findOAuthClient charge le client OAuth désigné par le paramètre :id.
+func findOAuthClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var client models.OAuthClient
+	if err := config.DB.First(&client, clientID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client OAuth non trouvé"})
+		return nil, false
+	}
+	return &client, true
+}
+
+// checkOAuthClientSettings vérifie les URI de retour (absolues, sans fragment) et les scopes d'un client.
+func checkOAuthClientSettings(c *gin.Context, redirectURIs, logoutURIs, scopes []string) bool {
+	for _, uri := range append(append([]string{}, redirectURIs...), logoutURIs...) {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" || strings.ContainsAny(uri, " \t\n") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "redirect_uri invalide : " + uri})
+			return false
+		}
+	}
+	for _, scope := range scopes {
+		if !utils.ValidOAuthScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scope inconnu : " + scope, "allowed_scopes": utils.OAuthScopes})
+			return false
+		}
+	}
+	return true
+}
+
+// GetOAuthClients liste les clients OAuth enregistrés.
+func GetOAuthClients(c *gin.Context) {
+	var clients []models.OAuthClient
+	if err := config.DB.Order("created_at desc").Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les clients OAuth"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": clients})
+}
+
+// GetOAuthClient retourne un client OAuth.
+func GetOAuthClient(c *gin.Context) {
+	client, ok := findOAuthClient(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"client": client})
+}
+
+// CreateOAuthClient enregistre un client OAuth. Le secret d'un client confidentiel n'est
+// renvoyé qu'une seule fois, dans cette réponse.
+func CreateOAuthClient(c *gin.Context) {
+	var input struct {
+		Name         string   `json:"name" binding:"required"`
+		Confidential bool     `json:"confidential"` // false : client public (SPA, mobile), PKCE seul
+		RedirectURIs []string `json:"redirect_uris" binding:"required,min=1"`
+		LogoutURIs   []string `json:"post_logout_redirect_uris"` // optionnel : retours après une déconnexion OpenID Connect
+		Scopes       []string `json:"scopes" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkOAuthClientSettings(c, input.RedirectURIs, input.LogoutURIs, input.Scopes) {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	client, secret, err := utils.CreateOAuthClient(input.Name, input.RedirectURIs, input.LogoutURIs, input.Scopes, input.Confidential, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client OAuth"})
+		return
+	}
+
+	response := gin.H{"message": "Client OAuth créé avec succès", "client": client}
+	if secret != "" {
+		response["message"] = "Client OAuth créé, conservez son secret : il ne sera plus affiché"
+		response["client_secret"] = secret
+	}
+	c.JSON(http.StatusCreated, response)
+	utils.LogActivity(userID, "oauth_client_created", "Création du client OAuth "+client.ClientID+" ("+client.Name+")")
+}
+
+// UpdateOAuthClient modifie le nom, les URI de retour et/ou les scopes d'un client OAuth.
+func UpdateOAuthClient(c *gin.Context) {
+	client, ok := findOAuthClient(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name         *string  `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`             // remplace la liste complète si présent
+		LogoutURIs   []string `json:"post_logout_redirect_uris"` // idem, une liste vide les retire toutes
+		Scopes       []string `json:"scopes"`                    // remplace la liste complète si présent
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (input.RedirectURIs != nil && len(input.RedirectURIs) == 0) || (input.Scopes != nil && len(input.Scopes) == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un client doit garder au moins une redirect_uri et un scope"})
+		return
+	}
+	if !checkOAuthClientSettings(c, input.RedirectURIs, input.LogoutURIs, input.Scopes) {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil && *input.Name != "" {
+		updates["name"] = *input.Name
+	}
+	if input.RedirectURIs != nil {
+		updates["redirect_uris"] = strings.Join(input.RedirectURIs, " ")
+	}
+	if input.LogoutURIs != nil {
+		updates["logout_uris"] = strings.Join(input.LogoutURIs, " ")
+	}
+	if input.Scopes != nil {
+		updates["scopes"] = strings.Join(input.Scopes, " ")
+	}
+	if len(updates) > 0 {
+		if err := config.DB.Model(client).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client OAuth mis à jour avec succès", "client": client})
+	utils.LogActivity(c.GetUint("user_id"), "oauth_client_updated", "Modification du client OAuth "+client.ClientID)
+}
+
+// RotateOAuthClientSecret remplace le secret d'un client confidentiel. L'ancien cesse
+// immédiatement de fonctionner.
+func RotateOAuthClientSecret(c *gin.Context) {
+	client, ok := findOAuthClient(c)
+	if !ok {
+		return
+	}
+	if client.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ce client OAuth est désactivé"})
+		return
+	}
+	if !client.Confidential {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un client public n'a pas de secret"})
+		return
+	}
+
+	secret, err := utils.RotateOAuthClientSecret(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du renouvellement du secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Secret renouvelé, conservez-le : il ne sera plus affiché",
+		"client_secret": secret,
+	})
+	utils.LogActivity(c.GetUint("user_id"), "oauth_client_secret_rotated", "Renouvellement du secret du client OAuth "+client.ClientID)
+}
+
+// DeleteOAuthClient désactive un client OAuth et ferme les sessions ouvertes à son nom.
+func DeleteOAuthClient(c *gin.Context) {
+	client, ok := findOAuthClient(c)
+	if !ok {
+		return
+	}
+	if client.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client OAuth non trouvé"})
+		return
+	}
+
+	if err := utils.RevokeOAuthClient(client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la désactivation du client OAuth"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client OAuth désactivé avec succès"})
+	utils.LogActivity(c.GetUint("user_id"), "oauth_client_revoked", "Désactivation du client OAuth "+client.ClientID)
+}
+
+// GetMyOAuthConsents liste les applications auxquelles l'utilisateur connecté a donné accès.
+func GetMyOAuthConsents(c *gin.Context) {
+	type consentView struct {
+		models.OAuthConsent
+		ClientName string `json:"client_name"`
+	}
+	var consents []consentView
+	err := config.DB.Model(&models.OAuthConsent{}).
+		Select("oauth_consents.*, oauth_clients.name AS client_name").
+		Joins("JOIN oauth_clients ON oauth_clients.client_id = oauth_consents.client_id").
+		Where("oauth_consents.user_id = ? AND oauth_clients.revoked_at IS NULL", c.GetUint("user_id")).
+		Order("oauth_consents.updated_at desc").
+		Scan(&consents).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les autorisations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": consents})
+}
+
+// DeleteMyOAuthConsent retire l'accès donné à une application et ferme ses sessions.
+func DeleteMyOAuthConsent(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	consentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var consent models.OAuthConsent
+	if err := config.DB.Where("id = ? AND user_id = ?", consentID, userID).First(&consent).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Autorisation non trouvée"})
+		return
+	}
+	if err := config.DB.Delete(&consent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := utils.RevokeOAuthClientSessions(consent.ClientID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Accès de l'application retiré avec succès"})
+	utils.LogActivity(userID, "oauth_consent_revoked", "Retrait de l'accès du client OAuth "+consent.ClientID)
+}
@@ -0,0 +1,150 @@
+// controllers/oidc.go
+
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// OpenIDConfiguration publie le document de découverte OpenID Connect
+// (OpenID Connect Discovery 1.0, section 4). Les URL dérivent de OIDC_ISSUER, sauf la page
+// d'autorisation (OIDC_AUTHORIZATION_URL) : sans elles, OpenID Connect est désactivé et le
+// document n'est pas publié.
+func OpenIDConfiguration(c *gin.Context) {
+	if !utils.OIDCAvailable() {
+		c.JSON(http.StatusNotFound, gin.H{"error": utils.ErrOIDCUnavailable.Error()})
+		return
+	}
+	issuer := utils.OIDCIssuer()
+	grantTypes := []string{"authorization_code", "refresh_token", "client_credentials"}
+
+	document := gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                utils.OIDCAuthorizationEndpoint(),
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"introspection_endpoint":                issuer + "/oauth/introspect",
+		"revocation_endpoint":                   issuer + "/oauth/revoke",
+		"end_session_endpoint":                  issuer + "/oauth/logout",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"scopes_supported":                      utils.OAuthScopes,
+		"response_types_supported":              []string{"code"},
+		"response_modes_supported":              []string{"query"},
+		"grant_types_supported":                 grantTypes,
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": utils.OIDCSigningAlgorithms(),
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"prompt_values_supported":               []string{"none", "consent"},
+		"acr_values_supported":                  []string{utils.ACRSingleFactor, utils.ACRMultiFactor},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr", "azp", "sid",
+			"preferred_username", "name", "picture", "updated_at", "email", "email_verified",
+		},
+	}
+	if deviceVerificationURI() != "" {
+		document["device_authorization_endpoint"] = issuer + "/oauth/device_authorization"
+		document["grant_types_supported"] = append(grantTypes, utils.OAuthDeviceCodeGrantType)
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, document)
+}
+
+// UserInfo retourne les claims de l'utilisateur autorisés par les scopes du token
+// (OpenID Connect Core, section 5.3). Requiert un token OAuth avec le scope openid.
+func UserInfo(c *gin.Context) {
+	scopes, _ := c.Get("scopes")
+	list, _ := scopes.([]string)
+	scope := strings.Join(list, " ")
+	if c.GetString("client_id") == "" || !utils.HasScope(scope, utils.ScopeOpenID) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope", "error_description": "token OAuth avec le scope openid requis"})
+		return
+	}
+
+	issuer := utils.OIDCIssuer()
+	if issuer == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": utils.ErrOIDCUnavailable.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil || user.DeletedAt != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": "compte utilisateur introuvable ou supprimé"})
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		Subject string `json:"sub"`
+		utils.OIDCUserClaims
+	}{
+		Subject:        strconv.FormatUint(uint64(user.ID), 10),
+		OIDCUserClaims: utils.UserClaimsForScope(&user, scope, issuer),
+	})
+}
+
+// EndSession déconnecte l'utilisateur d'une application à l'initiative de celle-ci
+// (OpenID Connect RP-Initiated Logout 1.0). L'id_token_hint désigne la session du client
+// à fermer ; le navigateur est ensuite renvoyé vers post_logout_redirect_uri si elle est
+// enregistrée pour ce client.
+func EndSession(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": err.Error()})
+		return
+	}
+	params := c.Request.Form
+	clientID := params.Get("client_id")
+
+	var claims *utils.IDTokenClaims
+	if hint := params.Get("id_token_hint"); hint != "" {
+		var err error
+		if claims, err = utils.ParseIDTokenHint(hint); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": "id_token_hint invalide"})
+			return
+		}
+		if clientID != "" && clientID != claims.AZP {
+			c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": "client_id différent de celui de l'id_token_hint"})
+			return
+		}
+		clientID = claims.AZP
+	}
+
+	// Vérifie la redirection avant de fermer quoi que ce soit
+	redirectURI := params.Get("post_logout_redirect_uri")
+	if redirectURI != "" {
+		client, err := utils.FindOAuthClient(clientID)
+		if err != nil || !utils.OAuthLogoutRedirectAllowed(client, redirectURI) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": utils.OAuthInvalidRequest, "error_description": "post_logout_redirect_uri non enregistrée pour ce client"})
+			return
+		}
+	}
+
+	if claims != nil {
+		userID, _ := strconv.ParseUint(claims.Subject, 10, 64)
+		sessionID, _ := strconv.ParseUint(claims.SID, 10, 64)
+		ended, err := utils.EndOAuthSession(uint(sessionID), uint(userID), clientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la fermeture de la session"})
+			return
+		}
+		if ended {
+			utils.LogActivity(uint(userID), "oauth_logout", "Déconnexion demandée par le client OAuth "+clientID)
+		}
+	}
+
+	if redirectURI != "" {
+		c.Redirect(http.StatusFound, oauthRedirect(redirectURI, params.Get("state"), url.Values{}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion effectuée"})
+}
@@ -0,0 +1,330 @@
+// controllers/organization.go
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/middleware"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/gorm"
+)
+
+// slugPattern : minuscules, chiffres et tirets, ex: "acme-france".
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// findOrganization charge l'organisation désignée par le paramètre :id.
+func findOrganization(c *gin.Context) (*models.Organization, bool) {
+	orgID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var org models.Organization
+	if err := config.DB.First(&org, orgID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organisation non trouvée"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return &org, true
+}
+
+// requireOrgPermission refuse la requête si l'utilisateur n'a pas la permission dans l'organisation.
+func requireOrgPermission(c *gin.Context, permission string, orgID uint) bool {
+	if !middleware.HasOrgPermission(c, permission, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission requise : " + permission})
+		return false
+	}
+	return true
+}
+
+// findMember charge l'appartenance désignée par le paramètre :user_id dans l'organisation.
+func findMember(c *gin.Context, orgID uint) (*models.Membership, bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	membership, err := utils.FindMembership(orgID, uint(userID))
+	if err != nil {
+		if err == utils.ErrNotMember {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Membre non trouvé"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return membership, true
+}
+
+// respondMembershipError traduit les erreurs de gestion des membres.
+func respondMembershipError(c *gin.Context, err error) {
+	switch err {
+	case utils.ErrRoleNotFound:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle inconnu"})
+	case utils.ErrAlreadyMember:
+		c.JSON(http.StatusConflict, gin.H{"error": "L'utilisateur est déjà membre de l'organisation"})
+	case utils.ErrLastOrgAdmin:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "L'organisation doit garder au moins un administrateur"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetOrganizations liste les organisations de l'utilisateur connecté, ou toutes avec la
+// permission globale orgs:manage.
+func GetOrganizations(c *gin.Context) {
+	var orgs []models.Organization
+	query := config.DB.Order("name")
+	if !middleware.GlobalPermissions(c)[utils.PermOrgsManage] {
+		query = query.Where("id IN (?)", config.DB.Model(&models.Membership{}).
+			Select("organization_id").Where("user_id = ?", c.GetUint("user_id")))
+	}
+	if err := query.Find(&orgs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les organisations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": orgs, "active_id": c.GetUint("org_id")})
+}
+
+// CreateOrganization crée une organisation ; son créateur en devient administrateur.
+func CreateOrganization(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+		Slug string `json:"slug" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slugPattern.MatchString(input.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug invalide : minuscules, chiffres et tirets uniquement"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	org, err := utils.CreateOrganization(input.Name, input.Slug, userID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Une organisation utilise déjà ce slug"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Organisation créée avec succès", "organization": org})
+	utils.LogActivity(userID, "org_created", fmt.Sprintf("Création de l'organisation %s (id=%d)", org.Slug, org.ID))
+}
+
+// GetOrganization retourne une organisation à ses membres.
+func GetOrganization(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok {
+		return
+	}
+	if !utils.IsMember(org.ID, c.GetUint("user_id")) && !middleware.GlobalPermissions(c)[utils.PermOrgsManage] {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation non trouvée"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization": org})
+}
+
+// UpdateOrganization renomme une organisation. Requiert orgs:manage dans l'organisation.
+func UpdateOrganization(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.DB.Model(org).Update("name", input.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organisation mise à jour avec succès", "organization": org})
+	utils.LogActivity(c.GetUint("user_id"), "org_updated", fmt.Sprintf("Modification de l'organisation %s (id=%d)", org.Slug, org.ID))
+}
+
+// DeleteOrganization supprime une organisation et toutes ses appartenances.
+// Requiert orgs:manage dans l'organisation.
+func DeleteOrganization(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	if err := utils.DeleteOrganization(org); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organisation supprimée avec succès"})
+	utils.LogActivity(c.GetUint("user_id"), "org_deleted", fmt.Sprintf("Suppression de l'organisation %s (id=%d)", org.Slug, org.ID))
+}
+
+// GetOrganizationMembers liste les membres d'une organisation et leurs rôles.
+// Requiert orgs:manage ou users:read dans l'organisation.
+func GetOrganizationMembers(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok {
+		return
+	}
+	if !middleware.HasOrgPermission(c, utils.PermUsersRead, org.ID) && !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	var members []models.Membership
+	if err := config.DB.Preload("User").Preload("Roles").
+		Where("organization_id = ?", org.ID).Order("created_at").
+		Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les membres"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// AddOrganizationMember rattache un compte existant à une organisation. L'ajout direct est
+// réservé aux détenteurs de la permission globale orgs:manage : un administrateur
+// d'organisation ne peut pas faire entrer un compte d'une autre organisation.
+func AddOrganizationMember(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok {
+		return
+	}
+	if !middleware.GlobalPermissions(c)[utils.PermOrgsManage] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission requise : " + utils.PermOrgsManage})
+		return
+	}
+
+	var input struct {
+		Email string   `json:"email" binding:"required,email"`
+		Roles []string `json:"roles"` // par défaut ["user"]
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ? AND deleted_at IS NULL", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	membership, err := utils.AddMember(org.ID, user.ID, input.Roles)
+	if err != nil {
+		respondMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Membre ajouté avec succès", "membership": membership})
+	utils.LogActivityBy(c.GetUint("user_id"), user.ID, "org_member_added", fmt.Sprintf("Ajout à l'organisation %s (id=%d)", org.Slug, org.ID))
+}
+
+// UpdateOrganizationMember remplace les rôles d'un membre. Requiert orgs:manage dans l'organisation.
+func UpdateOrganizationMember(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok || !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+	membership, ok := findMember(c, org.ID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Roles []string `json:"roles" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.SetMemberRoles(membership, input.Roles); err != nil {
+		respondMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôles du membre mis à jour avec succès", "membership": membership})
+	utils.LogActivityBy(c.GetUint("user_id"), membership.UserID, "org_member_updated", fmt.Sprintf("Rôles %v dans l'organisation %s (id=%d)", input.Roles, org.Slug, org.ID))
+}
+
+// RemoveOrganizationMember retire un membre d'une organisation. Requiert orgs:manage dans
+// l'organisation, sauf pour la quitter soi-même.
+func RemoveOrganizationMember(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok {
+		return
+	}
+	membership, ok := findMember(c, org.ID)
+	if !ok {
+		return
+	}
+	actorID := c.GetUint("user_id")
+	if membership.UserID != actorID && !requireOrgPermission(c, utils.PermOrgsManage, org.ID) {
+		return
+	}
+
+	if err := utils.RemoveMember(membership); err != nil {
+		respondMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Membre retiré avec succès"})
+	utils.LogActivityBy(actorID, membership.UserID, "org_member_removed", fmt.Sprintf("Retrait de l'organisation %s (id=%d)", org.Slug, org.ID))
+}
+
+// SwitchOrganization change l'organisation active de la session courante et renvoie un
+// token d'accès portant le nouveau claim "org_id". Les refresh suivants le conservent.
+func SwitchOrganization(c *gin.Context) {
+	org, ok := findOrganization(c)
+	if !ok {
+		return
+	}
+	userID := c.GetUint("user_id")
+	if !utils.IsMember(org.ID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Vous n'êtes pas membre de cette organisation"})
+		return
+	}
+	sessionID := c.GetUint("session_id")
+	if sessionID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Aucune session associée à ce token"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	session, err := utils.SetSessionOrganization(sessionID, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accessToken, err := utils.IssueAccessToken(&user, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organisation active modifiée",
+		"org_id":       org.ID,
+		"access_token": accessToken,
+	})
+	utils.LogActivity(userID, "org_switched", fmt.Sprintf("Organisation active : %s (id=%d)", org.Slug, org.ID))
+}
@@ -0,0 +1,263 @@
+// controllers/passkey.go
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/gorm"
+)
+
+// BeginPasskeyRegistration démarre l'enregistrement d'un passkey pour l'utilisateur connecté.
+// La réponse contient les options à passer à navigator.credentials.create().
+func BeginPasskeyRegistration(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input struct {
+		Name string `json:"name"` // optionnel : nom affiché dans la liste des passkeys
+	}
+	_ = c.ShouldBindJSON(&input)
+
+	wa, err := utils.WebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebAuthn n'est pas configuré"})
+		return
+	}
+	user, err := utils.LoadPasskeyUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	// Exclure les passkeys déjà enregistrés sur cet authentificateur
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Passkeys))
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, err := wa.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := utils.SavePasskeyChallenge(utils.CeremonyRegistration, userID, input.Name, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishPasskeyRegistration vérifie la réponse de navigator.credentials.create() et enregistre le passkey.
+func FinishPasskeyRegistration(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Réponse WebAuthn invalide"})
+		return
+	}
+
+	challenge, session, err := utils.ConsumePasskeyChallenge(utils.CeremonyRegistration, parsed.Response.CollectedClientData.Challenge)
+	if err != nil || challenge.UserID != userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge WebAuthn invalide ou expiré"})
+		return
+	}
+
+	wa, err := utils.WebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebAuthn n'est pas configuré"})
+		return
+	}
+	user, err := utils.LoadPasskeyUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	credential, err := wa.CreateCredential(user, *session, parsed)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vérification du passkey échouée"})
+		return
+	}
+
+	passkey := utils.NewPasskey(userID, challenge.Label, credential)
+	if err := config.DB.Create(&passkey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Passkey enregistré avec succès", "passkey": passkey})
+	utils.LogActivity(userID, "passkey_registered", "Enregistrement d'un passkey")
+}
+
+// GetMyPasskeys liste les passkeys de l'utilisateur connecté.
+func GetMyPasskeys(c *gin.Context) {
+	var passkeys []models.Passkey
+	if err := config.DB.Where("user_id = ?", c.GetUint("user_id")).Order("created_at desc").Find(&passkeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les passkeys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": passkeys})
+}
+
+// DeleteMyPasskey supprime un passkey de l'utilisateur connecté.
+func DeleteMyPasskey(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	passkeyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	result := config.DB.Where("id = ? AND user_id = ?", passkeyID, userID).Delete(&models.Passkey{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey non trouvé"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey supprimé avec succès"})
+	utils.LogActivity(userID, "passkey_deleted", "Suppression d'un passkey")
+}
+
+// BeginPasskeyLogin démarre une connexion par passkey. Sans email, la connexion
+// est "discoverable" : l'authentificateur choisit lui-même le compte.
+func BeginPasskeyLogin(c *gin.Context) {
+	var input struct {
+		Email      string `json:"email" binding:"omitempty,email"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wa, err := utils.WebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebAuthn n'est pas configuré"})
+		return
+	}
+
+	var options *protocol.CredentialAssertion
+	var session *webauthn.SessionData
+	var userID uint
+	if input.Email != "" {
+		var user models.User
+		if err := config.DB.Where("email = ? AND deleted_at IS NULL", input.Email).First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Aucun passkey pour ce compte"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		passkeyUser, err := utils.LoadPasskeyUser(user.ID)
+		if err != nil || len(passkeyUser.Passkeys) == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Aucun passkey pour ce compte"})
+			return
+		}
+		userID = user.ID
+		options, session, err = wa.BeginLogin(passkeyUser)
+	} else {
+		options, session, err = wa.BeginDiscoverableLogin()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.SavePasskeyChallenge(utils.CeremonyLogin, userID, input.DeviceName, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishPasskeyLogin vérifie la réponse de navigator.credentials.get() et émet
+// le même couple access/refresh token que Login.
+func FinishPasskeyLogin(c *gin.Context) {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Réponse WebAuthn invalide"})
+		return
+	}
+
+	challenge, session, err := utils.ConsumePasskeyChallenge(utils.CeremonyLogin, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Challenge WebAuthn invalide ou expiré"})
+		return
+	}
+
+	wa, err := utils.WebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebAuthn n'est pas configuré"})
+		return
+	}
+
+	var user *utils.PasskeyUser
+	var credential *webauthn.Credential
+	if challenge.UserID != 0 {
+		if user, err = utils.LoadPasskeyUser(challenge.UserID); err == nil {
+			credential, err = wa.ValidateLogin(user, *session, parsed)
+		}
+	} else {
+		// Connexion discoverable : le user handle renvoyé par l'authentificateur désigne le compte
+		credential, err = wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			id, err := strconv.ParseUint(string(userHandle), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			user, err = utils.LoadPasskeyUser(uint(id))
+			return user, err
+		}, *session, parsed)
+	}
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentification par passkey échouée"})
+		return
+	}
+
+	// Un compte verrouillé ou une adresse IP bloquée le restent, comme pour Login
+	if wait := utils.LoginRetryAfter(user.User, user.User.Email, c.ClientIP()); wait > 0 {
+		tooManyLoginAttempts(c, wait)
+		return
+	}
+
+	// Un compteur de signature qui recule trahit un authentificateur cloné
+	if credential.Authenticator.CloneWarning {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentification par passkey échouée"})
+		utils.LogActivity(user.User.ID, "passkey_clone_warning", "Compteur de signature incohérent, authentificateur possiblement cloné")
+		return
+	}
+
+	if !requireVerifiedEmail(c, user.User) {
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(&models.Passkey{}).
+		Where("user_id = ? AND credential_id = ?", user.User.ID, utils.EncodeCredentialID(credential.ID)).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": now,
+		})
+
+	respondWithLoginTokens(c, user.User, challenge.Label, []string{utils.AMRHardwareKey, utils.AMRUserPresence}, "Utilisateur connecté avec succès (passkey)")
+}
@@ -0,0 +1,68 @@
+// controllers/password_reset.go
+
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// ForgotPassword envoie un lien de réinitialisation. La réponse est toujours 202,
+// que le compte existe ou non, pour ne pas révéler les adresses inscrites.
+func ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ? AND deleted_at IS NULL", input.Email).First(&user).Error; err == nil {
+		token, err := utils.CreatePasswordResetToken(user.ID, c.ClientIP())
+		if err != nil {
+			log.Println("Erreur lors de la création du token de réinitialisation:", err)
+		} else if err := utils.SendPasswordResetEmail(&user, token); err != nil {
+			log.Println("Erreur lors de l'envoi de l'email de réinitialisation:", err)
+		} else {
+			utils.LogActivity(user.ID, "password_reset", "Demande de réinitialisation depuis "+c.ClientIP())
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Si un compte existe pour cette adresse, un email de réinitialisation a été envoyé"})
+}
+
+// ResetPassword remplace le mot de passe à partir du token reçu par email
+// et déconnecte l'utilisateur de tous ses appareils.
+func ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := utils.ResetPassword(input.Token, input.Password)
+	if err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		if err == utils.ErrInvalidResetToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token de réinitialisation invalide ou expiré"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la réinitialisation du mot de passe"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mot de passe réinitialisé avec succès, reconnectez-vous"})
+	utils.LogActivity(user.ID, "password_reset", "Mot de passe réinitialisé, toutes les sessions ont été révoquées")
+}
@@ -0,0 +1,353 @@
+// controllers/role.go
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/gorm"
+)
+
+// findPermissions charge les permissions demandées et signale les noms inconnus.
+func findPermissions(c *gin.Context, names []string) ([]models.Permission, bool) {
+	permissions := []models.Permission{}
+	if len(names) == 0 {
+		return permissions, true
+	}
+	if err := config.DB.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(permissions) != len(names) {
+		known := map[string]bool{}
+		for _, p := range permissions {
+			known[p.Name] = true
+		}
+		for _, name := range names {
+			if !known[name] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Permission inconnue : " + name})
+				return nil, false
+			}
+		}
+	}
+	return permissions, true
+}
+
+// findRole charge le rôle désigné par le paramètre :id.
+func findRole(c *gin.Context) (*models.Role, bool) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var role models.Role
+	if err := config.DB.Preload("Permissions").First(&role, roleID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rôle non trouvé"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return &role, true
+}
+
+// GetPermissions liste les permissions disponibles.
+func GetPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	if err := config.DB.Order("name").Find(&permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": permissions})
+}
+
+// GetRoles liste les rôles et leurs permissions.
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les rôles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": roles})
+}
+
+// CreateRole crée un rôle avec une liste de permissions.
+func CreateRole(c *gin.Context) {
+	var input struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	permissions, ok := findPermissions(c, input.Permissions)
+	if !ok {
+		return
+	}
+
+	role := models.Role{Name: input.Name, Description: input.Description, Permissions: permissions}
+	if err := config.DB.Create(&role).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Un rôle porte déjà ce nom"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Rôle créé avec succès", "role": role})
+	utils.LogActivity(c.GetUint("user_id"), "role_created", "Création du rôle "+role.Name)
+}
+
+// UpdateRole modifie la description et/ou les permissions d'un rôle.
+// Les permissions du rôle admin ne sont pas modifiables : il les a toutes.
+func UpdateRole(c *gin.Context) {
+	role, ok := findRole(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Description *string  `json:"description"`
+		Permissions []string `json:"permissions"` // remplace la liste complète si présent
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Description != nil {
+		if err := config.DB.Model(role).Update("description", *input.Description).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.Permissions != nil {
+		if role.Name == utils.RoleAdmin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Les permissions du rôle admin ne sont pas modifiables"})
+			return
+		}
+		permissions, ok := findPermissions(c, input.Permissions)
+		if !ok {
+			return
+		}
+		if err := config.DB.Model(role).Association("Permissions").Replace(permissions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle mis à jour avec succès", "role": role})
+	utils.LogActivity(c.GetUint("user_id"), "role_updated", "Modification du rôle "+role.Name)
+}
+
+// DeleteRole supprime un rôle et le retire de ses membres, de ses groupes et des organisations.
+// Les rôles système sont protégés.
+func DeleteRole(c *gin.Context) {
+	role, ok := findRole(c)
+	if !ok {
+		return
+	}
+	if role.System {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un rôle système ne peut pas être supprimé"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM membership_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle supprimé avec succès"})
+	utils.LogActivity(c.GetUint("user_id"), "role_deleted", "Suppression du rôle "+role.Name)
+}
+
+// GetUserRoles liste les rôles d'un utilisateur, ses groupes (directs et parents) et ses
+// permissions effectives.
+func GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	permissions, err := utils.UserPermissions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupIDs, err := utils.UserGroupIDs(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	groups := []models.Group{}
+	if len(groupIDs) > 0 {
+		if err := config.DB.Order("name").Find(&groups, groupIDs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": user.Roles, "groups": groups, "permissions": permissions})
+}
+
+// AssignUserRole attribue un rôle à un utilisateur.
+func AssignUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	role, err := utils.AssignRole(user.ID, input.Role)
+	if err != nil {
+		if err == utils.ErrRoleNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle inconnu : " + input.Role})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle attribué avec succès"})
+	utils.LogActivityBy(c.GetUint("user_id"), user.ID, "role_assigned", fmt.Sprintf("Rôle %s attribué par l'utilisateur %d", role.Name, c.GetUint("user_id")))
+}
+
+// SetUserRole change le rôle principal d'un utilisateur (celui du claim "role").
+// L'ancien rôle principal lui est retiré ; le dernier administrateur ne peut pas être rétrogradé.
+func SetUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	previous, err := utils.SetPrimaryRole(user.ID, input.Role)
+	switch err {
+	case nil:
+	case utils.ErrRoleNotFound:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle inconnu : " + input.Role})
+		return
+	case utils.ErrLastAdmin:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Impossible de rétrograder le dernier administrateur"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle mis à jour avec succès", "role": input.Role})
+	if previous != input.Role {
+		actorID := c.GetUint("user_id")
+		utils.LogActivityBy(actorID, user.ID, "role_changed", fmt.Sprintf("Rôle principal %s → %s, modifié par l'utilisateur %d", previous, input.Role, actorID))
+	}
+}
+
+// RemoveUserRole retire un rôle à un utilisateur. Le rôle principal se change avec SetUserRole,
+// et le dernier administrateur ne peut pas perdre son rôle.
+func RemoveUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	roleID, err := strconv.Atoi(c.Param("role_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de rôle invalide"})
+		return
+	}
+
+	var role models.Role
+	if err := config.DB.First(&role, roleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rôle non trouvé"})
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+	if user.Role == role.Name {
+		c.JSON(http.StatusConflict, gin.H{"error": "Rôle principal de l'utilisateur : changez-le d'abord avec PUT /api/users/:id/role"})
+		return
+	}
+	if role.Name == utils.RoleAdmin {
+		var others int64
+		config.DB.Table("user_roles").Where("role_id = ? AND user_id <> ?", role.ID, userID).Count(&others)
+		if others == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Impossible de retirer le rôle au dernier administrateur"})
+			return
+		}
+	}
+
+	// Le rôle principal a pu changer entre-temps : il n'est jamais retiré ici
+	result := config.DB.Exec(`DELETE FROM user_roles WHERE user_id = ? AND role_id = ?
+		AND NOT EXISTS (SELECT 1 FROM users WHERE id = ? AND role = ?)`, userID, role.ID, userID, role.Name)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "L'utilisateur n'a pas ce rôle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle retiré avec succès"})
+	utils.LogActivityBy(c.GetUint("user_id"), uint(userID), "role_removed", fmt.Sprintf("Rôle %s retiré par l'utilisateur %d", role.Name, c.GetUint("user_id")))
+}
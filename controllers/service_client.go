@@ -0,0 +1,167 @@
+// controllers/service_client.go
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// findServiceClient charge le client de service désigné par le paramètre :id.
+func findServiceClient(c *gin.Context) (*models.ServiceClient, bool) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+	var client models.ServiceClient
+	if err := config.DB.Where("revoked_at IS NULL").First(&client, clientID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client de service non trouvé"})
+		return nil, false
+	}
+	return &client, true
+}
+
+// checkServiceScopes vérifie la forme des scopes d'un client de service.
+func checkServiceScopes(c *gin.Context, scopes []string) bool {
+	for _, scope := range scopes {
+		if !utils.ValidServiceScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scope invalide : " + scope})
+			return false
+		}
+	}
+	return true
+}
+
+// GetServiceClients liste les clients de service.
+func GetServiceClients(c *gin.Context) {
+	var clients []models.ServiceClient
+	if err := config.DB.Order("created_at desc").Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les clients de service"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": clients})
+}
+
+// GetServiceClient retourne un client de service.
+func GetServiceClient(c *gin.Context) {
+	client, ok := findServiceClient(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"client": client})
+}
+
+// CreateServiceClient enregistre un client de service. Son secret n'est renvoyé qu'une
+// seule fois, dans cette réponse.
+func CreateServiceClient(c *gin.Context) {
+	var input struct {
+		Name   string   `json:"name" binding:"required"`
+		Scopes []string `json:"scopes" binding:"required,min=1"` // ex: ["orders:read", "orders:write"]
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkServiceScopes(c, input.Scopes) {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	client, secret, err := utils.CreateServiceClient(input.Name, input.Scopes, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client de service"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Client de service créé, conservez son secret : il ne sera plus affiché",
+		"client":        client,
+		"client_secret": secret,
+	})
+	utils.LogActivity(userID, "service_client_created", "Création du client de service "+client.ClientID+" ("+client.Name+")")
+}
+
+// UpdateServiceClient modifie le nom et/ou les scopes d'un client de service. Les tokens
+// déjà émis gardent leurs scopes jusqu'à leur expiration.
+func UpdateServiceClient(c *gin.Context) {
+	client, ok := findServiceClient(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name   *string  `json:"name"`
+		Scopes []string `json:"scopes"` // remplace la liste complète si présent
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Scopes != nil && len(input.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un client de service doit garder au moins un scope"})
+		return
+	}
+	if !checkServiceScopes(c, input.Scopes) {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil && *input.Name != "" {
+		updates["name"] = *input.Name
+	}
+	if input.Scopes != nil {
+		updates["scopes"] = strings.Join(input.Scopes, " ")
+	}
+	if len(updates) > 0 {
+		if err := config.DB.Model(client).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client de service mis à jour avec succès", "client": client})
+	utils.LogActivity(c.GetUint("user_id"), "service_client_updated", "Modification du client de service "+client.ClientID)
+}
+
+// RotateServiceClientSecret remplace le secret d'un client de service.
+func RotateServiceClientSecret(c *gin.Context) {
+	client, ok := findServiceClient(c)
+	if !ok {
+		return
+	}
+
+	secret, err := utils.RotateServiceClientSecret(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du renouvellement du secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Secret renouvelé, conservez-le : il ne sera plus affiché",
+		"client_secret": secret,
+	})
+	utils.LogActivity(c.GetUint("user_id"), "service_client_secret_rotated", "Renouvellement du secret du client de service "+client.ClientID)
+}
+
+// DeleteServiceClient désactive un client de service ; ses tokens sont refusés immédiatement.
+func DeleteServiceClient(c *gin.Context) {
+	client, ok := findServiceClient(c)
+	if !ok {
+		return
+	}
+
+	if err := utils.RevokeServiceClient(client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la désactivation du client de service"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client de service désactivé avec succès"})
+	utils.LogActivity(c.GetUint("user_id"), "service_client_revoked", "Désactivation du client de service "+client.ClientID)
+}
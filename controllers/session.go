@@ -0,0 +1,122 @@
+// controllers/session.go
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/middleware"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/gorm"
+)
+
+// listActiveSessions retourne les sessions ouvertes d'un utilisateur, la plus récente en premier.
+func listActiveSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := config.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// revokeUserSession ferme la session sessionID si elle appartient à userID.
+func revokeUserSession(c *gin.Context, userID uint, sessionIDParam string) bool {
+	sessionID, err := strconv.Atoi(sessionIDParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de session invalide"})
+		return false
+	}
+
+	var session models.Session
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session non trouvée"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return false
+	}
+
+	if err := utils.RevokeSession(&session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la fermeture de la session"})
+		return false
+	}
+	return true
+}
+
+// GetMySessions liste les sessions ouvertes de l'utilisateur connecté.
+func GetMySessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	sessions, err := listActiveSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       sessions,
+		"current_id": c.GetUint("session_id"),
+	})
+}
+
+// DeleteMySession ferme une session de l'utilisateur connecté (déconnexion d'un appareil).
+func DeleteMySession(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if !revokeUserSession(c, userID, c.Param("id")) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session fermée avec succès"})
+	utils.LogActivity(userID, "revoke_session", "Fermeture d'une session par l'utilisateur")
+}
+
+// GetUserSessions liste les sessions ouvertes d'un utilisateur.
+// Requiert la permission sessions:admin sur cet utilisateur.
+func GetUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	if !middleware.HasPermissionFor(c, utils.PermSessionsAdmin, uint(userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
+		return
+	}
+
+	sessions, err := listActiveSessions(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Impossible de récupérer les sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// DeleteUserSession ferme une session d'un utilisateur.
+// Requiert la permission sessions:admin sur cet utilisateur.
+func DeleteUserSession(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	if !middleware.HasPermissionFor(c, utils.PermSessionsAdmin, uint(userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
+		return
+	}
+
+	if !revokeUserSession(c, uint(userID), c.Param("session_id")) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session fermée avec succès"})
+	utils.LogActivity(uint(userID), "revoke_session", "Fermeture d'une session par un admin")
+}
@@ -3,24 +3,27 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/middleware"
 	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/passwords"
 	"github.com/kdev1966/go-auth-api/utils"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// GetAllUsers récupère tous les utilisateurs (pour un usage admin, par exemple).
+// GetAllUsers récupère les utilisateurs : tous avec la permission globale users:read,
+// ceux de l'organisation active avec la permission donnée par un rôle de membre.
 func GetAllUsers(c *gin.Context) {
-	// Vérifie si l'utilisateur est admin
-	role, _ := c.Get("role")
-	if role != "admin" {
+	// Vérifie que l'utilisateur peut consulter tous les comptes
+	if !middleware.HasPermission(c, utils.PermUsersRead) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès interdit"})
 		return
 	}
@@ -43,6 +46,14 @@ func GetAllUsers(c *gin.Context) {
 	var users []models.User
 	query := config.DB.Model(&models.User{})
 
+	// Sans permission globale, seuls les membres de l'organisation active sont visibles ;
+	// avec, le paramètre org_id permet de filtrer sur une organisation
+	if !middleware.GlobalPermissions(c)[utils.PermUsersRead] {
+		query = query.Where("id IN (?)", utils.OrgMemberIDs(c.GetUint("org_id")))
+	} else if orgID, err := strconv.Atoi(c.Query("org_id")); err == nil {
+		query = query.Where("id IN (?)", utils.OrgMemberIDs(uint(orgID)))
+	}
+
 	// Recherche (username ou email)
 	if search != "" {
 		query = query.Where("username ILIKE ? OR email ILIKE ?", "%"+search+"%", "%"+search+"%")
@@ -68,7 +79,7 @@ func GetAllUsers(c *gin.Context) {
 
 // GetUserByID godoc
 // @Summary      Obtenir un utilisateur par son ID
-// @Description  Accessible par un admin ou l'utilisateur lui-même
+// @Description  Accessible avec la permission users:read ou par l'utilisateur lui-même
 // @Tags         users
 // @Security     BearerAuth
 // @Param        id   path      string  true  "ID de l'utilisateur"
@@ -98,10 +109,9 @@ func GetUserByID(c *gin.Context) {
 
 	// Récupération des infos depuis le token (middleware)
 	tokenUsername, _ := c.Get("username")
-	tokenRole, _ := c.Get("role")
 
-	// Vérification d'accès : admin ou propriétaire du compte
-	if tokenRole != "admin" && tokenUsername != user.Username {
+	// Vérification d'accès : permission users:read sur ce compte ou propriétaire du compte
+	if !middleware.HasPermissionFor(c, utils.PermUsersRead, user.ID) && tokenUsername != user.Username {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
 		return
 	}
@@ -110,7 +120,7 @@ func GetUserByID(c *gin.Context) {
 }
 
 // UpdateUser met à jour les informations d'un utilisateur.
-// Seul l'utilisateur lui-même peut mettre à jour son profil.
+// Seul l'utilisateur lui-même ou un détenteur de users:update peut mettre à jour le profil.
 func UpdateUser(c *gin.Context) {
 	// Paramètre ID
 	idParam := c.Param("id")
@@ -126,7 +136,6 @@ func UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
 		return
 	}
-	tokenRole, _ := c.Get("role")
 
 	tokenUserID, ok := tokenUserIDRaw.(uint)
 	if !ok {
@@ -134,8 +143,8 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	// Autorisation : admin ou le bon utilisateur
-	if tokenRole != "admin" && tokenUserID != uint(userID) {
+	// Autorisation : permission users:update ou le bon utilisateur
+	if !middleware.HasPermission(c, utils.PermUsersUpdate) && tokenUserID != uint(userID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
 		return
 	}
@@ -165,16 +174,22 @@ func UpdateUser(c *gin.Context) {
 	if input.Username != "" {
 		user.Username = input.Username
 	}
-	if input.Email != "" {
+	emailChanged := input.Email != "" && input.Email != user.Email
+	if emailChanged {
+		// La nouvelle adresse doit être vérifiée à son tour
 		user.Email = input.Email
+		user.EmailVerifiedAt = nil
 	}
 	if input.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if respondPasswordPolicyError(c, passwords.Validate(input.Password, user.Username, user.Email)) {
+			return
+		}
+		hashedPassword, err := passwords.Hash(input.Password)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur de hachage du mot de passe"})
 			return
 		}
-		user.Password = string(hashedPassword)
+		user.Password = hashedPassword
 	}
 
 	if err := config.DB.Save(&user).Error; err != nil {
@@ -182,11 +197,17 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if emailChanged {
+		if err := utils.SendVerificationEmail(&user); err != nil {
+			log.Println("Erreur lors de l'envoi de l'email de vérification:", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Utilisateur mis à jour avec succès", "user": user})
 }
 
-// DeleteUser supprime un utilisateur.
-// Seul l'utilisateur lui-même ou un admin peut réaliser cette opération.
+// DeleteUser désactive un utilisateur (suppression logique, voir RestoreUser).
+// Seul l'utilisateur lui-même ou un détenteur de users:delete peut réaliser cette opération.
 func DeleteUser(c *gin.Context) {
 	// Paramètre ID
 	idParam := c.Param("id")
@@ -202,7 +223,6 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
 		return
 	}
-	tokenRole, _ := c.Get("role")
 
 	tokenUserID, ok := tokenUserIDRaw.(uint)
 	if !ok {
@@ -210,14 +230,18 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// Autorisation
-	if tokenRole != "admin" && tokenUserID != uint(userID) {
+	// Autorisation : permission users:delete ou le bon utilisateur
+	if !middleware.HasPermission(c, utils.PermUsersDelete) && tokenUserID != uint(userID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
 		return
 	}
 
-	// Suppression
-	if err := config.DB.Delete(&models.User{}, userID).Error; err != nil {
+	// Suppression logique : le compte reste restaurable, ses sessions sont fermées
+	if err := utils.SoftDeleteUser(uint(userID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -229,7 +253,7 @@ func DeleteUser(c *gin.Context) {
 }
 
 // HardDeleteUser supprime définitivement un utilisateur de la base de données.
-// Seul un admin peut réaliser cette opération.
+// Requiert la permission users:purge.
 func HardDeleteUser(c *gin.Context) {
 	// Récupération de l'ID depuis les paramètres
 	idParam := c.Param("id")
@@ -239,24 +263,29 @@ func HardDeleteUser(c *gin.Context) {
 		return
 	}
 
-	// Récupération du rôle depuis le contexte
-	role, exists := c.Get("role")
-	if !exists || role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé : réservé aux administrateurs"})
+	// Vérification de la permission
+	if !middleware.HasPermission(c, utils.PermUsersPurge) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé : permission users:purge requise"})
 		return
 	}
 
-	// Suppression définitive de l'utilisateur
-	if err := config.DB.Unscoped().Delete(&models.User{}, userID).Error; err != nil {
+	// Suppression définitive de l'utilisateur et de ce qui lui est rattaché
+	if err := utils.PurgeUser(uint(userID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression définitive de l'utilisateur"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Utilisateur supprimé définitivement avec succès"})
+	actorID := c.GetUint("user_id")
+	utils.LogActivityBy(actorID, uint(userID), "user_purged", fmt.Sprintf("Suppression définitive du compte par l'utilisateur %d", actorID))
 }
 
 // RestoreUser restaure un utilisateur supprimé (soft delete).
-// Seul un admin peut réaliser cette opération.
+// Requiert la permission users:restore.
 func RestoreUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := strconv.Atoi(idParam)
@@ -265,9 +294,8 @@ func RestoreUser(c *gin.Context) {
 		return
 	}
 
-	// Vérifie que l'utilisateur est admin
-	tokenRole, _ := c.Get("role")
-	if tokenRole != "admin" {
+	// Vérifie que l'utilisateur peut restaurer un compte
+	if !middleware.HasPermission(c, utils.PermUsersRestore) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Seul un administrateur peut restaurer un utilisateur"})
 		return
 	}
@@ -285,6 +313,34 @@ func RestoreUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Utilisateur restauré avec succès"})
 }
 
+// UnlockUser lève le verrouillage d'un compte après trop d'échecs de connexion.
+// Requiert la permission users:unlock.
+func UnlockUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if !middleware.HasPermissionFor(c, utils.PermUsersUnlock, uint(userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
+		return
+	}
+
+	found, err := utils.UnlockAccount(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Compte déverrouillé avec succès"})
+	utils.LogActivity(uint(userID), "account_unlocked", fmt.Sprintf("Compte déverrouillé par l'administrateur %d", c.GetUint("user_id")))
+}
+
 // GetMe godoc
 // @Summary      Retourne le profil utilisateur
 // @Description  Donne les infos de l'utilisateur connecté
@@ -0,0 +1,137 @@
+// controllers/user_test.go
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB ouvre une base SQLite en mémoire, clés étrangères actives comme sous PostgreSQL.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?_foreign_keys=on"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&models.Permission{},
+		&models.Role{},
+		&models.User{},
+		&models.Group{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.ActivityLog{},
+		&models.RefreshToken{},
+		&models.Session{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+	return db
+}
+
+// deleteUserRequest appelle DeleteUser pour targetID, authentifié en tant que actorID.
+func deleteUserRequest(actorID, targetID uint, permissions map[string]bool) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	id := strconv.FormatUint(uint64(targetID), 10)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/api/users/"+id, nil)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Set("user_id", actorID)
+	c.Set("permissions", permissions)
+	DeleteUser(c)
+	return w
+}
+
+func TestDeleteUser(t *testing.T) {
+	db := openTestDB(t)
+
+	role := models.Role{Name: "user"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	newUser := func(name string) models.User {
+		user := models.User{Username: name, Email: name + "@example.com", Password: "x", Role: "user", Roles: []models.Role{role}}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+
+	org := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatal(err)
+	}
+	records := []interface{}{
+		&models.Membership{OrganizationID: org.ID, UserID: alice.ID, Roles: []models.Role{role}},
+		&models.Group{Name: "support", Members: []models.User{alice}},
+		&models.Session{UserID: alice.ID, FamilyID: "family", RefreshTokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)},
+		&models.RefreshToken{UserID: alice.ID, JTI: "jti", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		actorID     uint
+		targetID    uint
+		permissions map[string]bool
+		want        int
+	}{
+		{"autre compte sans permission", bob.ID, alice.ID, map[string]bool{}, http.StatusForbidden},
+		{"son propre compte", alice.ID, alice.ID, map[string]bool{}, http.StatusOK},
+		{"compte déjà supprimé", bob.ID, alice.ID, map[string]bool{utils.PermUsersDelete: true}, http.StatusNotFound},
+		{"autre compte avec users:delete", alice.ID, bob.ID, map[string]bool{utils.PermUsersDelete: true}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := deleteUserRequest(tt.actorID, tt.targetID, tt.permissions); w.Code != tt.want {
+				t.Fatalf("code %d, attendu %d : %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// Suppression logique : le compte et ses rattachements restent, il est restaurable
+	var deleted models.User
+	if err := db.First(&deleted, alice.ID).Error; err != nil {
+		t.Fatalf("compte supprimé physiquement : %v", err)
+	}
+	if deleted.DeletedAt == nil || deleted.TokensRevokedAt == nil {
+		t.Error("deleted_at et tokens_revoked_at doivent être renseignés")
+	}
+	var count int64
+	db.Table("user_roles").Where("user_id = ?", alice.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("user_roles : %d ligne(s), attendu 1", count)
+	}
+
+	// Ses sessions et refresh tokens sont révoqués
+	db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", alice.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d session(s) encore active(s)", count)
+	}
+	db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", alice.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d refresh token(s) encore actif(s)", count)
+	}
+}
@@ -0,0 +1,39 @@
+// mailer/file.go
+
+package mailer
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer ajoute les emails à un fichier au lieu de les envoyer (développement).
+type FileMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+// NewFileMailer crée un mailer qui écrit dans path.
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{Path: path, From: from}
+}
+
+// Send ajoute le message à la fin du fichier.
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString("--- " + time.Now().Format(time.RFC3339) + "\r\n"); err != nil {
+		return err
+	}
+	_, err = f.Write(format(m.From, msg))
+	return err
+}
@@ -0,0 +1,87 @@
+// mailer/mailer.go
+// Package mailer envoie les emails transactionnels de l'API (vérification
+// d'adresse, réinitialisation de mot de passe...).
+
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Message est un email texte simple.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer est implémenté par chaque mode d'envoi.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var (
+	defaultOnce   sync.Once
+	defaultMailer Mailer
+)
+
+// Default retourne le mailer configuré par MAIL_DRIVER :
+//   - "smtp" : envoi via SMTP_HOST / SMTP_PORT (ex: MailHog ou Mailpit en local)
+//   - "file" (défaut) : les emails sont ajoutés à MAIL_LOG_FILE (défaut mail.log)
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		switch strings.ToLower(os.Getenv("MAIL_DRIVER")) {
+		case "smtp":
+			defaultMailer = NewSMTPMailer(
+				os.Getenv("SMTP_HOST"),
+				os.Getenv("SMTP_PORT"),
+				os.Getenv("SMTP_USERNAME"),
+				os.Getenv("SMTP_PASSWORD"),
+				sender(),
+			)
+		default:
+			path := os.Getenv("MAIL_LOG_FILE")
+			if path == "" {
+				path = "mail.log"
+			}
+			defaultMailer = NewFileMailer(path, sender())
+		}
+	})
+	return defaultMailer
+}
+
+// SetDefault remplace le mailer par défaut (utile pour brancher un autre transport).
+func SetDefault(m Mailer) {
+	defaultOnce.Do(func() {})
+	defaultMailer = m
+}
+
+// Send envoie un message avec le mailer par défaut.
+func Send(msg Message) error {
+	return Default().Send(msg)
+}
+
+func sender() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	return "no-reply@localhost"
+}
+
+// format construit le message au format RFC 5322.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
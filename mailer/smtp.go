@@ -0,0 +1,33 @@
+// mailer/smtp.go
+
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer envoie les emails via un serveur SMTP.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// NewSMTPMailer crée un mailer SMTP. Sans identifiants, aucune authentification
+// n'est faite, ce qui convient aux serveurs de test locaux.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	if port == "" {
+		port = "25"
+	}
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{Addr: net.JoinHostPort(host, port), Auth: auth, From: from}
+}
+
+// Send envoie le message.
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, format(m.From, msg))
+}
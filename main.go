@@ -5,6 +5,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -15,6 +16,7 @@ import (
 	_ "github.com/kdev1966/go-auth-api/docs" // nécessaire pour les fichiers générés par swag
 	"github.com/kdev1966/go-auth-api/models"
 	"github.com/kdev1966/go-auth-api/routes"
+	"github.com/kdev1966/go-auth-api/utils"
 )
 
 // @title           Go Auth API
@@ -45,10 +47,63 @@ func main() {
 	config.ConnectDatabase()
 
 	// Migration automatique du modèle
-	if err := config.DB.AutoMigrate(&models.User{}, &models.ActivityLog{}); err != nil {
+	if err := config.DB.AutoMigrate(
+		&models.Permission{},
+		&models.Role{},
+		&models.User{},
+		&models.Group{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.ActivityLog{},
+		&models.RevokedToken{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.SigningKey{},
+		&models.RecoveryCode{},
+		&models.MFAAttempt{},
+		&models.Passkey{},
+		&models.PasskeyChallenge{},
+		&models.PasswordResetToken{},
+		&models.LoginThrottle{},
+		&models.LoginEmailThrottle{},
+		&models.APIKey{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthConsent{},
+		&models.OAuthDeviceCode{},
+		&models.ServiceClient{},
+	); err != nil {
 		log.Fatal("Erreur lors de la migration de la base de données:", err)
 	}
-	log.Println("Migration réussie pour le modèle User et ActivityLog.")
+	log.Println("Migration réussie des modèles.")
+
+	// Rôles et permissions par défaut
+	if err := utils.SeedRBAC(); err != nil {
+		log.Fatal("Erreur lors de l'initialisation des rôles:", err)
+	}
+
+	// Premier administrateur à partir de ADMIN_EMAIL / ADMIN_PASSWORD, s'il n'y en a aucun
+	if err := utils.SeedAdminFromEnv(); err != nil {
+		log.Fatal("Erreur lors de la création du premier administrateur:", err)
+	}
+
+	// Chargement du trousseau de clés de signature des tokens
+	if err := utils.InitKeyring(); err != nil {
+		log.Fatal("Erreur lors du chargement des clés de signature JWT:", err)
+	}
+
+	// Commande d'administration (ex: rotate-keys) au lieu du serveur
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
+	// Rechargement périodique du trousseau (rotation faite par une autre instance)
+	utils.StartKeyringRefresh(time.Minute)
+
+	// Purge périodique des tokens révoqués expirés
+	utils.StartRevocationCleanup(time.Hour)
 
 	// Configuration des routes via le package routes
 	router := routes.SetupRoutes()
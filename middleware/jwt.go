@@ -2,18 +2,22 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/utils"
 )
 
 // AuthMiddleware vérifie la validité du token JWT et injecte les claims dans le contexte.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Clé d'API : en-tête X-API-Key, ou Authorization: Bearer pat_...
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		// Récupérer le token depuis l'en-tête Authorization
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -31,35 +35,126 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		mySigningKey := []byte(os.Getenv("JWT_SECRET"))
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Vérifier le type d'algorithme de signature
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-			}
-			return mySigningKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+		if utils.IsAPIKey(tokenString) {
+			authenticateAPIKey(c, tokenString)
 			return
 		}
 
-		// Extraire les claims et les placer dans le contexte
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if ok && token.Valid {
-			// Extraction de l'ID utilisateur, en supposant qu'il est stocké comme un nombre
-			if userID, ok := claims["user_id"].(float64); ok {
-				c.Set("user_id", uint(userID))
+		// Vérifier la signature (clé choisie d'après le kid), l'expiration, le type du token
+		// et qu'il n'a pas été révoqué (logout, session fermée)
+		claims, err := utils.ValidateAccessToken(tokenString)
+		if err != nil {
+			message := "Invalid or expired token"
+			switch err {
+			case utils.ErrTokenRevoked:
+				message = "Token has been revoked"
+			case utils.ErrSessionClosed:
+				message = "Session has been closed"
 			}
-			c.Set("username", claims["username"])
-			c.Set("role", claims["role"])
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+			c.Abort()
+			return
+		}
+		// Token de service (grant client_credentials) : aucun utilisateur derrière
+		if serviceClientID := claims.ServiceClientID(); serviceClientID != "" {
+			authenticateServiceToken(c, claims, serviceClientID)
+			return
 		}
+		userID := claims.UserID()
+
+		// Placer les claims dans le contexte
+		c.Set("claims", claims)
+		c.Set("user_id", userID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("scopes", claims.Scopes)
+		c.Set("jti", claims.ID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("org_id", claims.OrgID)
+		c.Set("client_id", claims.ClientID)
+		c.Set("token_exp", claims.ExpiresAt.Unix())
 
 		c.Next()
 	}
 }
 
+// authenticateAPIKey authentifie la requête avec une clé d'API. Le contexte reçoit les
+// mêmes clés qu'avec un JWT, avec les droits actuels du propriétaire et les scopes de la clé.
+func authenticateAPIKey(c *gin.Context, rawKey string) {
+	key, user, err := utils.AuthenticateAPIKey(rawKey)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API key"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
+	c.Set("scopes", utils.APIKeyScopeList(key))
+	c.Set("api_key_id", key.ID)
+
+	c.Next()
+}
+
+// authenticateServiceToken authentifie la requête avec un token de service. Le contexte
+// reçoit "service_client_id" au lieu de "user_id" ; les routes agissant pour un utilisateur
+// les refusent avec RequireUser.
+func authenticateServiceToken(c *gin.Context, claims *utils.Claims, serviceClientID string) {
+	c.Set("claims", claims)
+	c.Set("service_client_id", serviceClientID)
+	c.Set("client_id", serviceClientID)
+	c.Set("scopes", claims.Scopes)
+	c.Set("jti", claims.ID)
+	c.Set("token_exp", claims.ExpiresAt.Unix())
+
+	c.Next()
+}
+
+// RequireUser refuse les tokens de service : la route agit pour le compte d'un utilisateur.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("service_client_id") != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Route réservée aux utilisateurs, inaccessible avec un token de service"})
+			return
+		}
+		c.Next()
+	}
+}
 
+// RequireScope refuse les requêtes dont les scopes ne contiennent pas scope.
+// Une requête sans scopes (session interactive, clé d'API sans restriction) passe.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("scopes")
+		list, _ := scopes.([]string)
+		if len(list) == 0 {
+			c.Next()
+			return
+		}
+		for _, s := range list {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Scope insuffisant", "required_scope": scope})
+	}
+}
 
+// RejectAPIKeys réserve une route aux sessions interactives : la gestion du compte
+// (sessions, 2FA, passkeys, clés d'API) n'est accessible ni avec une clé d'API, ni avec
+// un token délivré à un client OAuth.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetUint("api_key_id") != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Action impossible avec une clé d'API"})
+			return
+		}
+		if c.GetString("client_id") != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Action impossible avec un token délivré à une application tierce"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+// middleware/permission.go
+
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kdev1966/go-auth-api/utils"
+)
+
+// Permissions retourne les permissions de l'utilisateur authentifié : celles de ses rôles
+// globaux, plus celles de ses rôles dans l'organisation active (claim "org_id"). Elles sont
+// lues en base au premier appel puis gardées dans le contexte pour le reste de la requête :
+// un changement de rôle s'applique dès la requête suivante, sans attendre un nouveau token.
+func Permissions(c *gin.Context) map[string]bool {
+	if cached, ok := c.Get("permissions"); ok {
+		return cached.(map[string]bool)
+	}
+
+	permissions := map[string]bool{}
+	for name := range GlobalPermissions(c) {
+		permissions[name] = true
+	}
+	if userID, orgID := c.GetUint("user_id"), c.GetUint("org_id"); userID != 0 && orgID != 0 {
+		names, err := utils.MembershipPermissions(userID, orgID)
+		if err != nil {
+			log.Println("Erreur lors de la lecture des permissions de l'organisation:", err)
+		}
+		for _, name := range names {
+			permissions[name] = true
+		}
+	}
+	c.Set("permissions", permissions)
+	return permissions
+}
+
+// GlobalPermissions retourne les permissions données par les rôles globaux de l'utilisateur,
+// attribués directement ou par ses groupes, valables sur tous les comptes quelle que soit
+// leur organisation.
+func GlobalPermissions(c *gin.Context) map[string]bool {
+	if cached, ok := c.Get("global_permissions"); ok {
+		return cached.(map[string]bool)
+	}
+
+	permissions := map[string]bool{}
+	if userID := c.GetUint("user_id"); userID != 0 {
+		names, err := utils.UserPermissions(userID)
+		if err != nil {
+			log.Println("Erreur lors de la lecture des permissions:", err)
+		}
+		for _, name := range names {
+			permissions[name] = true
+		}
+	}
+	c.Set("global_permissions", permissions)
+	return permissions
+}
+
+// HasPermission indique si l'utilisateur authentifié dispose d'une permission.
+func HasPermission(c *gin.Context, permission string) bool {
+	return Permissions(c)[permission]
+}
+
+// HasPermissionFor indique si l'utilisateur authentifié dispose d'une permission sur le
+// compte targetID : permission globale, ou permission dans l'organisation active dont
+// targetID est membre. Un administrateur d'organisation n'a aucun droit hors de celle-ci.
+func HasPermissionFor(c *gin.Context, permission string, targetID uint) bool {
+	if GlobalPermissions(c)[permission] {
+		return true
+	}
+	orgID := c.GetUint("org_id")
+	return orgID != 0 && HasPermission(c, permission) && utils.IsMember(orgID, targetID)
+}
+
+// HasOrgPermission indique si l'utilisateur authentifié dispose d'une permission dans une
+// organisation donnée (active ou non) : par un rôle global ou par ses rôles de membre.
+func HasOrgPermission(c *gin.Context, permission string, orgID uint) bool {
+	if GlobalPermissions(c)[permission] {
+		return true
+	}
+	names, err := utils.MembershipPermissions(c.GetUint("user_id"), orgID)
+	if err != nil {
+		log.Println("Erreur lors de la lecture des permissions de l'organisation:", err)
+	}
+	for _, name := range names {
+		if name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission refuse la requête si l'utilisateur n'a pas toutes les permissions demandées.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if !HasPermission(c, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission requise : " + permission})
+				return
+			}
+		}
+		c.Next()
+	}
+}
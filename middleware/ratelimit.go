@@ -0,0 +1,165 @@
+// middleware/ratelimit.go
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitKeyFunc détermine le compteur auquel une requête est rattachée.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP limite chaque adresse IP séparément.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser limite chaque utilisateur authentifié (à placer après AuthMiddleware),
+// et se rabat sur l'adresse IP pour les requêtes anonymes.
+func KeyByUser(c *gin.Context) string {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return KeyByIP(c)
+}
+
+// KeyByRoute applique une limite globale à la route, toutes origines confondues.
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + ":" + c.FullPath()
+}
+
+// RateLimitPolicy est une limite nommée : au plus Limit requêtes par Window et par clé.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// NewRateLimitPolicy crée une politique dont la limite peut être remplacée par la variable
+// d'environnement RATE_LIMIT_<NAME>, au format "<requêtes>/<durée>" (ex: "5/1m").
+// La valeur "off" désactive la politique.
+func NewRateLimitPolicy(name, defaultSpec string, key RateLimitKeyFunc) RateLimitPolicy {
+	policy := RateLimitPolicy{Name: name, Key: key}
+	spec := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+	if spec == "" {
+		spec = defaultSpec
+	}
+	if strings.EqualFold(spec, "off") {
+		return policy
+	}
+
+	limit, window, err := parseRateLimitSpec(spec)
+	if err != nil {
+		log.Printf("RATE_LIMIT_%s invalide (%v), valeur par défaut utilisée : %s", strings.ToUpper(name), err, defaultSpec)
+		limit, window, _ = parseRateLimitSpec(defaultSpec)
+	}
+	policy.Limit, policy.Window = limit, window
+	return policy
+}
+
+func parseRateLimitSpec(spec string) (int, time.Duration, error) {
+	count, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("format attendu <requêtes>/<durée>")
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit < 1 {
+		return 0, 0, fmt.Errorf("nombre de requêtes invalide")
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || window < time.Second {
+		return 0, 0, fmt.Errorf("durée invalide")
+	}
+	return limit, window, nil
+}
+
+var (
+	rateLimitStoreOnce sync.Once
+	rateLimitStore     RateLimitStore
+)
+
+// DefaultRateLimitStore retourne le store choisi par RATE_LIMIT_STORE :
+//   - "memory" (défaut) : compteurs propres à l'instance
+//   - "redis" : compteurs partagés entre instances, via REDIS_URL (ex: redis://localhost:6379/0)
+func DefaultRateLimitStore() RateLimitStore {
+	rateLimitStoreOnce.Do(func() {
+		if strings.EqualFold(os.Getenv("RATE_LIMIT_STORE"), "redis") {
+			opts, err := redis.ParseURL(os.Getenv("REDIS_URL"))
+			if err != nil {
+				log.Fatal("REDIS_URL invalide:", err)
+			}
+			rateLimitStore = NewRedisRateLimitStore(redis.NewClient(opts), "ratelimit:")
+			return
+		}
+		rateLimitStore = NewMemoryRateLimitStore()
+	})
+	return rateLimitStore
+}
+
+// SetRateLimitStore remplace le store par défaut (à appeler avant SetupRoutes).
+func SetRateLimitStore(store RateLimitStore) {
+	rateLimitStoreOnce.Do(func() {})
+	rateLimitStore = store
+}
+
+// RateLimit applique une politique de limitation. Les réponses portent les en-têtes
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset et RateLimit-Policy ; une requête
+// refusée reçoit 429 avec Retry-After. Si le store est indisponible, la requête passe.
+func RateLimit(policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Limit == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if policy.Key == nil {
+		policy.Key = KeyByIP
+	}
+	store := DefaultRateLimitStore()
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 500*time.Millisecond)
+		defer cancel()
+
+		key := policy.Name + ":" + policy.Key(c)
+		result, err := store.Allow(ctx, key, policy.Limit, policy.Window)
+		if err != nil {
+			log.Println("Erreur du rate limiter, requête autorisée:", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+
+		if !result.Allowed {
+			retryAfter := max(ceilSeconds(result.RetryAfter), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Trop de requêtes, réessayez plus tard",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,71 @@
+// middleware/ratelimit_redis.go
+
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript incrémente le compteur de la fenêtre courante si la limite le permet.
+// Exécuté de façon atomique par Redis, il peut être partagé par plusieurs instances de l'API.
+//
+// KEYS[1] : compteur de la fenêtre courante, KEYS[2] : compteur de la fenêtre précédente
+// ARGV[1] : limite, ARGV[2] : fenêtre (ms), ARGV[3] : temps écoulé dans la fenêtre (ms)
+// Retourne {autorisé (0/1), compte précédent, compte courant avant incrément}.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local estimated = previous * (window - elapsed) / window + current
+if estimated + 1 > limit then
+	return {0, previous, current}
+end
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], window * 2)
+return {1, previous, current}
+`)
+
+// RedisRateLimitStore partage les compteurs entre toutes les instances via Redis.
+type RedisRateLimitStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisRateLimitStore crée un store Redis ; les clés sont préfixées par prefix.
+func NewRedisRateLimitStore(client redis.UniversalClient, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+// Allow comptabilise une requête pour key.
+func (s *RedisRateLimitStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	// L'heure de Redis sert de référence commune à toutes les instances
+	now, err := s.client.Time(ctx).Result()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	start := now.Truncate(window)
+	elapsed := now.Sub(start).Truncate(time.Millisecond)
+	index := start.UnixMilli() / window.Milliseconds()
+	// Le hash tag {key} place les deux fenêtres sur le même slot de Redis Cluster, qui refuse
+	// sinon un script portant sur plusieurs slots (CROSSSLOT)
+	currentKey := s.prefix + "{" + key + "}:" + strconv.FormatInt(index, 10)
+	previousKey := s.prefix + "{" + key + "}:" + strconv.FormatInt(index-1, 10)
+
+	values, err := slidingWindowScript.Run(ctx, s.client,
+		[]string{currentKey, previousKey},
+		limit, window.Milliseconds(), elapsed.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	result, _ := slidingWindow(values[1], values[2], elapsed, window, limit)
+	result.Allowed = values[0] == 1
+	return result, nil
+}
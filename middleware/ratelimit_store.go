@@ -0,0 +1,114 @@
+// middleware/ratelimit_store.go
+
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitResult décrit l'état d'une limite après une requête.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // temps avant que la fenêtre courante se termine
+	RetryAfter time.Duration // attente conseillée quand la requête est refusée
+}
+
+// RateLimitStore conserve les compteurs des limites. Les implémentations appliquent
+// une fenêtre glissante : le compte de la fenêtre précédente est pondéré par la part
+// de celle-ci encore couverte par la fenêtre glissante.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// slidingWindow calcule le résultat à partir des compteurs des deux fenêtres fixes.
+// Commun aux implémentations pour qu'elles aient exactement le même comportement.
+func slidingWindow(previous, current int64, elapsed, window time.Duration, limit int) (RateLimitResult, bool) {
+	weight := float64(window-elapsed) / float64(window)
+	estimated := float64(previous)*weight + float64(current)
+
+	result := RateLimitResult{Limit: limit, Reset: window - elapsed}
+	if estimated+1 > float64(limit) {
+		// Attendre que la part pondérée de la fenêtre précédente libère une place
+		result.RetryAfter = window - elapsed
+		if previous > 0 {
+			excess := estimated + 1 - float64(limit)
+			if wait := time.Duration(excess / float64(previous) * float64(window)); wait < result.RetryAfter {
+				result.RetryAfter = wait
+			}
+		}
+		return result, false
+	}
+
+	result.Allowed = true
+	if remaining := float64(limit) - estimated - 1; remaining > 0 {
+		result.Remaining = int(remaining)
+	}
+	return result, true
+}
+
+// MemoryRateLimitStore garde les compteurs en mémoire : adapté à une instance unique.
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	lastGC   time.Time
+}
+
+type memoryCounter struct {
+	window      time.Duration // fenêtre de la limite : les limites n'ont pas toutes la même
+	windowStart time.Time
+	previous    int64
+	current     int64
+}
+
+// NewMemoryRateLimitStore crée un store en mémoire.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counters: make(map[string]*memoryCounter), lastGC: time.Now()}
+}
+
+// Allow comptabilise une requête pour key.
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	start := now.Truncate(window)
+	s.gc(now)
+
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &memoryCounter{window: window, windowStart: start}
+		s.counters[key] = counter
+	}
+	switch {
+	case counter.windowStart.Equal(start):
+	case counter.windowStart.Add(window).Equal(start):
+		counter.previous, counter.current = counter.current, 0
+		counter.windowStart = start
+	default:
+		counter.previous, counter.current = 0, 0
+		counter.windowStart = start
+	}
+
+	result, allowed := slidingWindow(counter.previous, counter.current, now.Sub(start), window, limit)
+	if allowed {
+		counter.current++
+	}
+	return result, nil
+}
+
+// gc supprime de temps en temps les compteurs inactifs depuis plus de deux de leurs fenêtres.
+func (s *MemoryRateLimitStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < time.Minute {
+		return
+	}
+	s.lastGC = now
+	for key, counter := range s.counters {
+		if now.Sub(counter.windowStart) > 2*counter.window {
+			delete(s.counters, key)
+		}
+	}
+}
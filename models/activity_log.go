@@ -9,7 +9,8 @@ import (
 type ActivityLog struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	UserID    uint      `json:"user_id"`
-	Action    string    `json:"action"`  // ex: "login", "delete_account"
-	Details   string    `json:"details"` // optionnel : "a supprimé son compte", "changé son mot de passe", etc.
+	ActorID   *uint     `json:"actor_id,omitempty"` // auteur de l'action quand ce n'est pas l'utilisateur concerné (ex: un admin)
+	Action    string    `json:"action"`             // ex: "login", "delete_account"
+	Details   string    `json:"details"`            // optionnel : "a supprimé son compte", "changé son mot de passe", etc.
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,22 @@
+// models/api_key.go
+
+package models
+
+import (
+	"time"
+)
+
+// APIKey est un token d'accès personnel, utilisé par les scripts et la CI à la place de Login.
+// Seule l'empreinte de la clé est stockée ; Prefix permet de la reconnaître dans les listes.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`        // ex: "pat_3f9a1c2e"
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 de la clé complète
+	Scopes     string     `gorm:"type:text" json:"scopes"`       // séparés par des espaces, vide = tous
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`          // nil = sans expiration
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+// models/group.go
+
+package models
+
+import (
+	"time"
+)
+
+// Group regroupe des utilisateurs (ex: "support-tier-2") auxquels des rôles sont accordés.
+// Un groupe peut avoir un groupe parent : ses membres héritent alors aussi des rôles du parent.
+type Group struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+	ParentID    *uint     `gorm:"index" json:"parent_id,omitempty"`
+	Roles       []Role    `gorm:"many2many:group_roles;" json:"roles,omitempty"`
+	Members     []User    `gorm:"many2many:group_members;" json:"members,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
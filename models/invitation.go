@@ -0,0 +1,33 @@
+// models/invitation.go
+
+package models
+
+import (
+	"time"
+)
+
+// Statuts d'une invitation.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation invite une adresse email à rejoindre une organisation avec un rôle.
+// Le lien envoyé est un token signé ; seul son jti est conservé, et un renvoi le remplace.
+type Invitation struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	OrganizationID uint          `gorm:"index;not null" json:"organization_id"`
+	Organization   *Organization `json:"organization,omitempty"`
+	Email          string        `gorm:"index;not null" json:"email"`
+	Role           string        `gorm:"not null" json:"role"` // rôle attribué dans l'organisation à l'acceptation
+	InvitedByID    uint          `gorm:"not null" json:"invited_by_id"`
+	TokenID        string        `gorm:"uniqueIndex;not null" json:"-"` // jti du dernier lien envoyé
+	Status         string        `gorm:"index;not null;default:'pending'" json:"status"`
+	ExpiresAt      time.Time     `gorm:"not null" json:"expires_at"`
+	AcceptedByID   *uint         `json:"accepted_by_id,omitempty"`
+	RespondedAt    *time.Time    `json:"responded_at,omitempty"` // acceptation, refus ou révocation
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+// models/login_throttle.go
+
+package models
+
+import (
+	"time"
+)
+
+// LoginThrottle compte les échecs de connexion par adresse IP, tous comptes confondus.
+type LoginThrottle struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	IP             string     `gorm:"uniqueIndex;not null" json:"ip"`
+	FailedAttempts int        `gorm:"default:0;not null" json:"failed_attempts"`
+	LastFailedAt   time.Time  `gorm:"index" json:"last_failed_at"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+}
+
+// LoginEmailThrottle compte les échecs de connexion sur un email qui ne correspond à aucun
+// compte, pour lui imposer les mêmes délais et le même verrouillage qu'à un compte existant.
+// Seule l'empreinte de l'email normalisé est stockée.
+type LoginEmailThrottle struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	EmailHash      string     `gorm:"uniqueIndex;not null" json:"-"`
+	FailedAttempts int        `gorm:"default:0;not null" json:"failed_attempts"`
+	LastFailedAt   time.Time  `gorm:"index" json:"last_failed_at"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+}
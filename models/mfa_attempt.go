@@ -0,0 +1,18 @@
+// models/mfa_attempt.go
+
+package models
+
+import (
+	"time"
+)
+
+// MFAAttempt compte les codes présentés avec un token "mfa_pending", identifié par son jti.
+// Les entrées expirées sont purgées en tâche de fond.
+type MFAAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JTI       string    `gorm:"uniqueIndex;not null" json:"jti"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Attempts  int       `gorm:"default:0;not null" json:"attempts"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // expiration du token
+	CreatedAt time.Time `json:"created_at"`
+}
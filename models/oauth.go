@@ -0,0 +1,83 @@
+// models/oauth.go
+
+package models
+
+import (
+	"time"
+)
+
+// OAuthClient est une application autorisée à demander l'accès aux comptes des utilisateurs.
+// Un client confidentiel (application serveur) s'authentifie avec son secret ; un client
+// public (SPA, application mobile) n'en a pas et s'appuie uniquement sur PKCE.
+type OAuthClient struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ClientID     string     `gorm:"uniqueIndex;not null" json:"client_id"`
+	SecretHash   string     `json:"-"` // SHA-256 du secret, vide pour un client public
+	Name         string     `gorm:"not null" json:"name"`
+	Confidential bool       `gorm:"not null" json:"confidential"`
+	RedirectURIs string     `gorm:"type:text;not null" json:"redirect_uris"`    // séparées par des espaces, comparaison exacte
+	Scopes       string     `gorm:"type:text" json:"scopes"`                    // scopes que le client peut demander, séparés par des espaces
+	LogoutURIs   string     `gorm:"type:text" json:"post_logout_redirect_uris"` // retours acceptés après une déconnexion OpenID Connect
+	OwnerID      uint       `gorm:"not null" json:"owner_id"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// OAuthAuthorizationCode est un code d'autorisation à usage unique, lié au challenge PKCE
+// présenté à /oauth/authorize. Seule son empreinte est stockée.
+type OAuthAuthorizationCode struct {
+	ID            uint      `gorm:"primaryKey"`
+	CodeHash      string    `gorm:"uniqueIndex;not null"`
+	ClientID      string    `gorm:"index;not null"`
+	UserID        uint      `gorm:"not null"`
+	RedirectURI   string    `gorm:"type:text;not null"`
+	Scope         string    `gorm:"type:text"`
+	CodeChallenge string    `gorm:"not null"` // S256 uniquement
+	Nonce         string    `gorm:"type:text"`
+	AuthTime      time.Time // authentification de l'utilisateur, reprise par la session du client
+	AuthMethods   string
+	SessionID     uint      // session ouverte à l'échange, révoquée si le code est rejoué
+	ExpiresAt     time.Time `gorm:"index;not null"`
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+// OAuthConsent mémorise les scopes qu'un utilisateur a accordés à un client, pour ne pas
+// redemander son accord à chaque autorisation.
+type OAuthConsent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_oauth_consent_user_client;not null" json:"user_id"`
+	ClientID  string    `gorm:"uniqueIndex:idx_oauth_consent_user_client;not null" json:"client_id"`
+	Scope     string    `gorm:"type:text" json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthDeviceCode est une demande d'autorisation d'un appareil sans navigateur (RFC 8628).
+// L'appareil interroge /oauth/token avec le device_code, dont seule l'empreinte est stockée,
+// jusqu'à ce que l'utilisateur réponde en saisissant le user_code depuis un autre appareil.
+type OAuthDeviceCode struct {
+	ID             uint   `gorm:"primaryKey"`
+	DeviceCodeHash string `gorm:"uniqueIndex;not null"`
+	UserCode       string `gorm:"uniqueIndex;not null"` // sans tiret
+	ClientID       string `gorm:"index;not null"`
+	Scope          string `gorm:"type:text"`
+	UserID         uint   // utilisateur qui a répondu
+	ApprovedAt     *time.Time
+	DeniedAt       *time.Time
+	AuthTime       time.Time // authentification de l'utilisateur, reprise par la session du client
+	AuthMethods    string
+	PollInterval   int `gorm:"not null"` // délai minimal entre deux interrogations, en secondes
+	PollCount      int `gorm:"not null;default:0"`
+	LastPolledAt   *time.Time
+	ExpiresAt      time.Time `gorm:"index;not null"`
+	UsedAt         *time.Time
+	CreatedAt      time.Time
+}
+
+// Noms de tables explicites : par défaut GORM écrirait "o_auth_clients".
+func (OAuthClient) TableName() string            { return "oauth_clients" }
+func (OAuthAuthorizationCode) TableName() string { return "oauth_authorization_codes" }
+func (OAuthConsent) TableName() string           { return "oauth_consents" }
+func (OAuthDeviceCode) TableName() string        { return "oauth_device_codes" }
@@ -0,0 +1,28 @@
+// models/organization.go
+
+package models
+
+import (
+	"time"
+)
+
+// Organization est une organisation cliente (tenant). Un utilisateur peut appartenir à plusieurs.
+type Organization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Slug      string    `gorm:"uniqueIndex;not null" json:"slug"` // identifiant lisible, ex: "acme"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership rattache un utilisateur à une organisation. Ses rôles ne donnent des
+// permissions qu'au sein de cette organisation (voir utils.MembershipPermissions).
+type Membership struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	OrganizationID uint          `gorm:"uniqueIndex:idx_membership_org_user;not null" json:"organization_id"`
+	UserID         uint          `gorm:"uniqueIndex:idx_membership_org_user;index;not null" json:"user_id"`
+	User           *User         `json:"user,omitempty"`
+	Organization   *Organization `json:"organization,omitempty"`
+	Roles          []Role        `gorm:"many2many:membership_roles;" json:"roles,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+// models/passkey.go
+
+package models
+
+import (
+	"time"
+)
+
+// Passkey est un identifiant WebAuthn enregistré par un utilisateur.
+type Passkey struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"index;not null" json:"user_id"`
+	Name            string     `json:"name"`
+	CredentialID    string     `gorm:"uniqueIndex;not null" json:"credential_id"` // base64url
+	PublicKey       []byte     `gorm:"not null" json:"-"`                         // clé publique COSE
+	AttestationType string     `json:"attestation_type"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"sign_count"`
+	Transports      string     `json:"transports"` // liste séparée par des virgules (usb, nfc, internal...)
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PasskeyChallenge conserve l'état d'une cérémonie WebAuthn entre les étapes begin et finish.
+type PasskeyChallenge struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Challenge   string    `gorm:"uniqueIndex;not null" json:"-"`
+	UserID      uint      `gorm:"index" json:"user_id"`     // 0 pour une connexion sans identifiant (discoverable)
+	Ceremony    string    `gorm:"not null" json:"ceremony"` // "registration" ou "login"
+	Label       string    `json:"label"`                    // nom du passkey ou de l'appareil de connexion
+	SessionData string    `gorm:"type:text;not null" json:"-"`
+	ExpiresAt   time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
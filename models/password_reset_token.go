@@ -0,0 +1,19 @@
+// models/password_reset_token.go
+
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken est un token de réinitialisation de mot de passe à usage unique.
+// Seule son empreinte est stockée : le token en clair n'existe que dans l'email envoyé.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 du token
+	IP        string     `json:"ip"`                            // adresse à l'origine de la demande
+	ExpiresAt time.Time  `gorm:"index;not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
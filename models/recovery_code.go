@@ -0,0 +1,16 @@
+// models/recovery_code.go
+
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode est un code de secours à usage unique de la double authentification.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"` // SHA-256 du code
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+// models/refresh_token.go
+
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken trace chaque refresh token émis. Les tokens issus d'une même
+// connexion partagent un FamilyID : chaque rotation retire le précédent.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	JTI       string     `gorm:"uniqueIndex;not null" json:"-"`
+	FamilyID  string     `gorm:"index;not null" json:"family_id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`    // date de rotation (token retiré)
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // révocation explicite (logout, réutilisation détectée)
+	CreatedAt time.Time  `json:"created_at"`
+}
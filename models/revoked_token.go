@@ -0,0 +1,17 @@
+// models/revoked_token.go
+
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken référence un token (access ou refresh) invalidé avant son expiration.
+// Les entrées expirées sont purgées en tâche de fond.
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JTI       string    `gorm:"uniqueIndex;not null" json:"jti"` // identifiant unique du token (claim "jti")
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // expiration d'origine du token
+	CreatedAt time.Time `json:"created_at"`
+}
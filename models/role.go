@@ -0,0 +1,25 @@
+// models/role.go
+
+package models
+
+import (
+	"time"
+)
+
+// Permission est un droit élémentaire, ex: "users:delete".
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role regroupe des permissions et est attribué aux utilisateurs.
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	System      bool         `gorm:"default:false;not null" json:"system"` // rôle créé au démarrage, non supprimable
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
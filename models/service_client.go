@@ -0,0 +1,22 @@
+// models/service_client.go
+
+package models
+
+import (
+	"time"
+)
+
+// ServiceClient est un service interne qui obtient des tokens pour son propre compte, sans
+// utilisateur (grant client_credentials). Seule l'empreinte de son secret est stockée.
+type ServiceClient struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ClientID   string     `gorm:"uniqueIndex;not null" json:"client_id"` // ex: "svc_3f9a1c2e..."
+	SecretHash string     `gorm:"not null" json:"-"`                     // SHA-256 du secret
+	Name       string     `gorm:"not null" json:"name"`
+	Scopes     string     `gorm:"type:text;not null" json:"scopes"` // scopes permis, séparés par des espaces
+	OwnerID    uint       `gorm:"not null" json:"owner_id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+// models/session.go
+
+package models
+
+import (
+	"time"
+)
+
+// Session représente une connexion d'un utilisateur sur un appareil.
+// Chaque session est liée à une famille de refresh tokens.
+type Session struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID         string     `gorm:"uniqueIndex;not null" json:"-"` // famille de refresh tokens de la session
+	DeviceName       string     `json:"device_name"`
+	UserAgent        string     `gorm:"type:text" json:"user_agent"`
+	IP               string     `json:"ip"`
+	OrganizationID   *uint      `json:"organization_id,omitempty"`        // organisation active, reprise dans le claim "org_id"
+	ClientID         string     `gorm:"index" json:"client_id,omitempty"` // client OAuth ayant ouvert la session, vide pour une connexion directe
+	Scope            string     `json:"scope,omitempty"`                  // scopes accordés au client OAuth, séparés par des espaces
+	AuthMethods      string     `json:"auth_methods,omitempty"`           // méthodes d'authentification de l'utilisateur (RFC 8176), ex. "pwd otp mfa"
+	AuthTime         time.Time  `json:"auth_time"`                        // moment où l'utilisateur s'est authentifié
+	RefreshTokenHash string     `gorm:"not null" json:"-"`                // SHA-256 du refresh token courant
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+}
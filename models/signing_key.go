@@ -0,0 +1,20 @@
+// models/signing_key.go
+
+package models
+
+import (
+	"time"
+)
+
+// SigningKey est une clé du trousseau de signature des JWT.
+// Une seule clé est active ; les clés retirées vérifient encore les tokens
+// qu'elles ont signés jusqu'à ExpiresAt.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	KID        string     `gorm:"column:kid;uniqueIndex;not null" json:"kid"`
+	Algorithm  string     `gorm:"not null" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"` // PEM PKCS#8, ou secret HMAC en base64
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // fin de validité pour la vérification
+}
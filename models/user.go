@@ -21,10 +21,29 @@ type User struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"` // Utilisation de *time.Time ici
 
-	Username     string `gorm:"unique;not null" json:"username"`
-	Email        string `gorm:"unique;not null" json:"email"`
-	Password     string `gorm:"not null" json:"-"` // masqué dans les réponses JSON
-	Role         string `gorm:"default:'user';not null" json:"role"`
-	Avatar       string `gorm:"type:text" json:"avatar"`
-	RefreshToken string `gorm:"type:text" json:"-"`
+	Username string `gorm:"unique;not null" json:"username"`
+	Email    string `gorm:"unique;not null" json:"email"`
+	Password string `gorm:"not null" json:"-"` // masqué dans les réponses JSON
+	Role     string `gorm:"default:'user';not null" json:"role"`
+	Avatar   string `gorm:"type:text" json:"avatar"`
+
+	// Roles donnent les permissions de l'utilisateur (voir utils.UserPermissions) ;
+	// Role ci-dessus reste le rôle principal, repris dans le claim "role"
+	Roles []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+
+	// EmailVerifiedAt est renseigné une fois l'adresse confirmée via le lien envoyé par email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// Double authentification TOTP
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `gorm:"default:false;not null" json:"totp_enabled"`
+	TOTPLastStep int64  `json:"-"` // dernier pas de temps accepté, contre le rejeu d'un code
+
+	// Protection contre la force brute : échecs de connexion consécutifs et verrouillage temporaire
+	FailedLoginAttempts int        `gorm:"default:0;not null" json:"failed_login_attempts"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+
+	// TokensRevokedAt invalide tous les tokens émis avant cette date (logout sur tous les appareils)
+	TokensRevokedAt *time.Time `json:"-"`
 }
@@ -0,0 +1,151 @@
+// passwords/argon2id.go
+
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams sont les paramètres de coût d'Argon2id.
+type Argon2idParams struct {
+	Memory  uint32 // en KiB
+	Time    uint32 // nombre de passes
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2idParams suit les recommandations OWASP (64 MiB, 3 passes).
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+// Argon2idHasher produit des hachages au format PHC :
+// $argon2id$v=19$m=65536,t=3,p=2[,keyid=...]$<sel>$<hachage>
+type Argon2idHasher struct {
+	Params Argon2idParams
+	pepper []byte
+	keyID  string
+}
+
+// NewArgon2idHasher crée un Hasher Argon2id, avec un pepper optionnel.
+func NewArgon2idHasher(params Argon2idParams, pepperSecret string) *Argon2idHasher {
+	return &Argon2idHasher{Params: params, pepper: []byte(pepperSecret), keyID: pepperID([]byte(pepperSecret))}
+}
+
+// Recognizes reconnaît les hachages Argon2id.
+func (h *Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Hash calcule le hachage d'un mot de passe avec un sel aléatoire.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(pepper(h.pepper, password), salt, h.Params.Time, h.Params.Memory, h.Params.Threads, h.Params.KeyLen)
+
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", h.Params.Memory, h.Params.Time, h.Params.Threads)
+	if h.keyID != "" {
+		params += ",keyid=" + h.keyID
+	}
+	return fmt.Sprintf("$argon2id$v=%d$%s$%s$%s", argon2.Version, params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify recalcule le hachage avec les paramètres encodés et le compare en temps constant.
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	decoded, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	// Un hachage sans keyid date d'avant la configuration du pepper
+	var secret []byte
+	if decoded.keyID != "" {
+		if decoded.keyID != h.keyID {
+			return false, ErrPepperMismatch
+		}
+		secret = h.pepper
+	}
+
+	key := argon2.IDKey(pepper(secret, password), decoded.salt, decoded.params.Time, decoded.params.Memory, decoded.params.Threads, uint32(len(decoded.key)))
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1, nil
+}
+
+// NeedsRehash signale un hachage calculé avec d'autres paramètres ou un autre pepper.
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	decoded, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return decoded.params.Memory != h.Params.Memory ||
+		decoded.params.Time != h.Params.Time ||
+		decoded.params.Threads != h.Params.Threads ||
+		uint32(len(decoded.key)) != h.Params.KeyLen ||
+		decoded.keyID != h.keyID
+}
+
+type argon2idHash struct {
+	params Argon2idParams
+	keyID  string
+	salt   []byte
+	key    []byte
+}
+
+func decodeArgon2id(encoded string) (*argon2idHash, error) {
+	fields := phcFields(encoded)
+	if len(fields) != 5 || fields[0] != "argon2id" {
+		return nil, ErrUnknownHashFormat
+	}
+	if fields[1] != "v="+strconv.Itoa(argon2.Version) {
+		return nil, fmt.Errorf("version Argon2 non supportée : %s", fields[1])
+	}
+
+	decoded := &argon2idHash{}
+	for _, param := range strings.Split(fields[2], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		if name == "keyid" {
+			decoded.keyID = value
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("paramètre Argon2 invalide : %s", param)
+		}
+		switch name {
+		case "m":
+			decoded.params.Memory = uint32(n)
+		case "t":
+			decoded.params.Time = uint32(n)
+		case "p":
+			decoded.params.Threads = uint8(n)
+		}
+	}
+	if decoded.params.Memory == 0 || decoded.params.Time == 0 || decoded.params.Threads == 0 {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(fields[3]); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil || len(decoded.key) == 0 {
+		return nil, ErrUnknownHashFormat
+	}
+	return decoded, nil
+}
@@ -0,0 +1,38 @@
+// passwords/bcrypt.go
+
+package passwords
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher vérifie les hachages bcrypt créés avant le passage à Argon2id.
+// Ces hachages n'utilisent pas le pepper et sont toujours à recalculer.
+type BcryptHasher struct{}
+
+// Recognizes reconnaît les hachages bcrypt ($2a$, $2b$, $2y$).
+func (BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Hash calcule un hachage bcrypt (coût par défaut).
+func (BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Verify compare un mot de passe à un hachage bcrypt.
+func (BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash est toujours vrai : bcrypt n'est plus l'algorithme par défaut.
+func (BcryptHasher) NeedsRehash(string) bool {
+	return true
+}
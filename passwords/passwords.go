@@ -0,0 +1,113 @@
+// passwords/passwords.go
+// Package passwords hache et vérifie les mots de passe des utilisateurs.
+// Les nouveaux hachages utilisent Argon2id au format PHC ; les anciens hachages
+// bcrypt restent vérifiables et sont remplacés à la connexion suivante.
+
+package passwords
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownHashFormat est retournée pour un hachage qu'aucun Hasher ne reconnaît.
+var ErrUnknownHashFormat = errors.New("format de hachage inconnu")
+
+// ErrPepperMismatch est retournée quand le hachage a été calculé avec un autre pepper.
+var ErrPepperMismatch = errors.New("hachage calculé avec un autre pepper")
+
+// Hasher est implémenté par chaque algorithme de hachage.
+type Hasher interface {
+	// Recognizes indique si le hachage encodé a été produit par cet algorithme.
+	Recognizes(encoded string) bool
+	// Hash calcule le hachage encodé d'un mot de passe.
+	Hash(password string) (string, error)
+	// Verify compare un mot de passe à un hachage encodé.
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash indique si le hachage doit être recalculé avec les paramètres actuels.
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	configOnce sync.Once
+	current    Hasher
+	hashers    []Hasher
+)
+
+// configure lit la configuration au premier usage (après le chargement du .env) :
+//   - PASSWORD_PEPPER : secret optionnel mêlé aux mots de passe avant hachage,
+//     conservé hors de la base de données
+func configure() {
+	configOnce.Do(func() {
+		argon := NewArgon2idHasher(DefaultArgon2idParams, os.Getenv("PASSWORD_PEPPER"))
+		current = argon
+		hashers = []Hasher{argon, BcryptHasher{}}
+	})
+}
+
+// Hash hache un mot de passe avec l'algorithme par défaut (Argon2id).
+func Hash(password string) (string, error) {
+	configure()
+	return current.Hash(password)
+}
+
+// Verify compare un mot de passe à un hachage, quel que soit son algorithme.
+// needsRehash indique qu'il faut enregistrer un nouveau hachage (ancien algorithme,
+// paramètres trop faibles ou pepper absent) ; il n'a de sens que si ok est vrai.
+func Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	configure()
+	for _, h := range hashers {
+		if !h.Recognizes(encoded) {
+			continue
+		}
+		ok, err = h.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h != current || current.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownHashFormat
+}
+
+var (
+	dummyOnce sync.Once
+	dummyHash string
+)
+
+// VerifyDummy effectue une vérification factice, pour qu'une connexion avec un email
+// inconnu prenne autant de temps qu'avec un mauvais mot de passe.
+func VerifyDummy(password string) {
+	dummyOnce.Do(func() {
+		dummyHash, _ = Hash("go-auth-api")
+	})
+	Verify(password, dummyHash)
+}
+
+// pepper applique le pepper au mot de passe (HMAC-SHA256), ou le laisse tel quel sans pepper.
+func pepper(secret []byte, password string) []byte {
+	if len(secret) == 0 {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+// pepperID identifie un pepper sans le révéler (paramètre "keyid" du format PHC).
+func pepperID(secret []byte) string {
+	if len(secret) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(secret)
+	return base64.RawStdEncoding.EncodeToString(sum[:6])
+}
+
+// phcFields découpe un hachage au format PHC : $id$v=..$params$salt$hash.
+func phcFields(encoded string) []string {
+	return strings.Split(strings.TrimPrefix(encoded, "$"), "$")
+}
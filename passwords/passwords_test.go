@@ -0,0 +1,120 @@
+// passwords/passwords_test.go
+
+package passwords
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams garde les tests rapides ; seuls les paramètres encodés importent ici.
+var testParams = Argon2idParams{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func mustHash(t *testing.T, h Hasher, password string) string {
+	t.Helper()
+	encoded, err := h.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestHashVerify(t *testing.T) {
+	encoded, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		password        string
+		encoded         string
+		wantOK          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{"argon2id courant", "correct horse", encoded, true, false, nil},
+		{"argon2id mauvais mot de passe", "wrong horse", encoded, false, false, nil},
+		{"bcrypt hérité", "correct horse", string(legacy), true, true, nil},
+		{"bcrypt hérité mauvais mot de passe", "wrong horse", string(legacy), false, false, nil},
+		{"format inconnu", "correct horse", "$md5$abc", false, false, ErrUnknownHashFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := Verify(tt.password, tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erreur %v, attendu %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("ok=%v needsRehash=%v, attendu ok=%v needsRehash=%v", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestArgon2idPepper(t *testing.T) {
+	peppered := NewArgon2idHasher(testParams, "pepper-1")
+	rotated := NewArgon2idHasher(testParams, "pepper-2")
+	unpeppered := NewArgon2idHasher(testParams, "")
+
+	tests := []struct {
+		name    string
+		hasher  *Argon2idHasher
+		encoded string
+		wantOK  bool
+		wantErr error
+	}{
+		{"même pepper", peppered, mustHash(t, peppered, "secret"), true, nil},
+		{"autre pepper", rotated, mustHash(t, peppered, "secret"), false, ErrPepperMismatch},
+		{"pepper absent à la vérification", unpeppered, mustHash(t, peppered, "secret"), false, ErrPepperMismatch},
+		{"hachage antérieur au pepper", peppered, mustHash(t, unpeppered, "secret"), true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.hasher.Verify("secret", tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erreur %v, attendu %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("ok=%v, attendu %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	current := NewArgon2idHasher(testParams, "pepper")
+	with := func(change func(*Argon2idParams)) *Argon2idHasher {
+		params := testParams
+		change(&params)
+		return NewArgon2idHasher(params, "pepper")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"paramètres courants", mustHash(t, current, "secret"), false},
+		{"mémoire réduite", mustHash(t, with(func(p *Argon2idParams) { p.Memory = 512 }), "secret"), true},
+		{"autre nombre de passes", mustHash(t, with(func(p *Argon2idParams) { p.Time = 2 }), "secret"), true},
+		{"autre parallélisme", mustHash(t, with(func(p *Argon2idParams) { p.Threads = 2 }), "secret"), true},
+		{"clé plus courte", mustHash(t, with(func(p *Argon2idParams) { p.KeyLen = 16 }), "secret"), true},
+		{"sans pepper", mustHash(t, NewArgon2idHasher(testParams, ""), "secret"), true},
+		{"autre pepper", mustHash(t, NewArgon2idHasher(testParams, "ancien"), "secret"), true},
+		{"illisible", "$argon2id$v=19$m=0$$", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := current.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, attendu %v", got, tt.want)
+			}
+		})
+	}
+}
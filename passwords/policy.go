@@ -0,0 +1,205 @@
+// passwords/policy.go
+
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Violation est une règle de la politique de mot de passe non respectée.
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PolicyError regroupe les violations d'un mot de passe refusé.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	return "mot de passe non conforme à la politique"
+}
+
+// Policy décrit les règles imposées aux nouveaux mots de passe.
+type Policy struct {
+	MinLength      int
+	MaxLength      int
+	MinClasses     int    // nombre minimal de classes parmi minuscules, majuscules, chiffres, symboles
+	ForbidUserInfo bool   // refuser un mot de passe contenant le nom d'utilisateur ou l'email
+	CheckCommon    bool   // refuser les mots de passe de la liste embarquée
+	BreachDir      string // dossier des fichiers de préfixes SHA-1 (désactivé si vide)
+}
+
+var (
+	policyOnce    sync.Once
+	defaultPolicy Policy
+)
+
+// DefaultPolicy retourne la politique configurée par l'environnement :
+//   - PASSWORD_MIN_LENGTH (défaut 10), PASSWORD_MAX_LENGTH (défaut 128)
+//   - PASSWORD_MIN_CLASSES (défaut 2)
+//   - PASSWORD_CHECK_COMMON (défaut true)
+//   - PASSWORD_BREACH_DIR : copie locale de la liste des mots de passe compromis,
+//     un fichier <5 premiers caractères hexadécimaux du SHA-1>.txt contenant des lignes
+//     "<suffixe>:<occurrences>" (format de l'API range de Have I Been Pwned)
+func DefaultPolicy() Policy {
+	policyOnce.Do(func() {
+		defaultPolicy = Policy{
+			MinLength:      envInt("PASSWORD_MIN_LENGTH", 10),
+			MaxLength:      envInt("PASSWORD_MAX_LENGTH", 128),
+			MinClasses:     envInt("PASSWORD_MIN_CLASSES", 2),
+			ForbidUserInfo: true,
+			CheckCommon:    envBool("PASSWORD_CHECK_COMMON", true),
+			BreachDir:      os.Getenv("PASSWORD_BREACH_DIR"),
+		}
+	})
+	return defaultPolicy
+}
+
+// Validate vérifie un mot de passe avec la politique par défaut.
+// Retourne une *PolicyError si le mot de passe est refusé.
+func Validate(password, username, email string) error {
+	if violations := DefaultPolicy().Check(password, username, email); len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Check retourne toutes les règles non respectées par le mot de passe.
+func (p Policy) Check(password, username, email string) []Violation {
+	var violations []Violation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, Violation{Field: "password", Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		add("too_short", "Le mot de passe doit contenir au moins %d caractères", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add("too_long", "Le mot de passe ne doit pas dépasser %d caractères", p.MaxLength)
+	}
+	if p.MinClasses > 0 && characterClasses(password) < p.MinClasses {
+		add("too_simple", "Le mot de passe doit mélanger au moins %d types de caractères (minuscules, majuscules, chiffres, symboles)", p.MinClasses)
+	}
+
+	if p.ForbidUserInfo {
+		lower := strings.ToLower(password)
+		if username != "" && len(username) >= 3 && strings.Contains(lower, strings.ToLower(username)) {
+			add("contains_username", "Le mot de passe ne doit pas contenir le nom d'utilisateur")
+		}
+		if local, _, _ := strings.Cut(email, "@"); local != "" && len(local) >= 3 && strings.Contains(lower, strings.ToLower(local)) {
+			add("contains_email", "Le mot de passe ne doit pas contenir l'adresse email")
+		}
+	}
+
+	if p.CheckCommon && isCommonPassword(password) {
+		add("common", "Ce mot de passe fait partie des mots de passe les plus courants")
+	}
+	if p.BreachDir != "" {
+		breached, err := isBreached(p.BreachDir, password)
+		if err == nil && breached {
+			add("breached", "Ce mot de passe est apparu dans une fuite de données connue")
+		}
+	}
+
+	return violations
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var (
+	commonOnce      sync.Once
+	commonPasswords map[string]struct{}
+)
+
+// isCommonPassword compare le mot de passe (sans tenir compte de la casse) à la liste embarquée.
+func isCommonPassword(password string) bool {
+	commonOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		for _, line := range strings.Split(commonPasswordsList, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				commonPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+	_, found := commonPasswords[strings.ToLower(password)]
+	return found
+}
+
+// isBreached cherche le SHA-1 du mot de passe dans le fichier de son préfixe (k-anonymity) :
+// seul le fichier des hachages partageant les 5 premiers caractères est lu.
+func isBreached(dir, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n >= 0 {
+		return n
+	}
+	return fallback
+}
+
+func envBool(name string, fallback bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(name)); err == nil {
+		return b
+	}
+	return fallback
+}
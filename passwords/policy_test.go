@@ -0,0 +1,126 @@
+// passwords/policy_test.go
+
+package passwords
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBreachFile enregistre des mots de passe compromis au format de l'API range de
+// Have I Been Pwned : <préfixe>.txt contenant "<suffixe>:<occurrences>".
+func writeBreachFile(t *testing.T, dir string, counts map[string]string) {
+	t.Helper()
+	for password, count := range counts {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		f, err := os.OpenFile(filepath.Join(dir, hash[:5]+".txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Suffixe en minuscules : la comparaison ignore la casse
+		_, err = f.WriteString(strings.ToLower(hash[5:]) + ":" + count + "\r\n")
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func codes(violations []Violation) []string {
+	var list []string
+	for _, v := range violations {
+		list = append(list, v.Code)
+	}
+	return list
+}
+
+func TestPolicyCheck(t *testing.T) {
+	breachDir := t.TempDir()
+	writeBreachFile(t, breachDir, map[string]string{
+		"Leaked-Secret-2019": "42",
+		"Cleared-Secret-99":  "0",
+	})
+	policy := Policy{
+		MinLength:      10,
+		MaxLength:      20,
+		MinClasses:     3,
+		ForbidUserInfo: true,
+		CheckCommon:    true,
+		BreachDir:      breachDir,
+	}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		username string
+		email    string
+		want     []string
+	}{
+		{"conforme", policy, "Blue-Canyon-77", "alice", "alice@example.com", nil},
+		{"trop court", policy, "Ab1-xyz", "alice", "alice@example.com", []string{"too_short"}},
+		{"trop long", policy, "Blue-Canyon-77-Blue-Canyon", "alice", "alice@example.com", []string{"too_long"}},
+		{"longueur en caractères, pas en octets", policy, "Éléphant-évasé-9", "alice", "alice@example.com", nil},
+		{"pas assez de classes", policy, "bluecanyonsky", "alice", "alice@example.com", []string{"too_simple"}},
+		{"contient le nom d'utilisateur", policy, "Xx-ALICE-2024", "alice", "bob@example.com", []string{"contains_username"}},
+		{"nom d'utilisateur trop court ignoré", policy, "Blue-Al-Canyon-7", "al", "bob@example.com", nil},
+		{"contient l'email", policy, "Robert.Smith-9", "bob", "robert.smith@example.com", []string{"contains_email"}},
+		{"informations personnelles autorisées", Policy{ForbidUserInfo: false}, "alice-secret", "alice", "alice@example.com", nil},
+		{"mot de passe courant", policy, "Qwerty123", "alice", "alice@example.com", []string{"too_short", "common"}},
+		{"liste courante désactivée", Policy{CheckCommon: false}, "qwerty123", "", "", nil},
+		{"compromis", policy, "Leaked-Secret-2019", "alice", "alice@example.com", []string{"breached"}},
+		{"compromis sans occurrence", policy, "Cleared-Secret-99", "alice", "alice@example.com", nil},
+		{"préfixe absent", policy, "Unlisted-Prefix-1", "alice", "alice@example.com", nil},
+		{"vérification des fuites désactivée", Policy{}, "Leaked-Secret-2019", "", "", nil},
+		{"toutes les règles", policy, "alice", "alice", "alice@example.com", []string{"too_short", "too_simple", "contains_username", "contains_email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := tt.policy.Check(tt.password, tt.username, tt.email)
+			if got := codes(violations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations %v, attendu %v", got, tt.want)
+			}
+			for _, v := range violations {
+				if v.Field != "password" || v.Message == "" {
+					t.Errorf("violation incomplète : %+v", v)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"conforme", "Blue-Canyon-77", nil},
+		{"refusé", "password", []string{"too_short", "too_simple", "common"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.password, "alice", "alice@example.com")
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("erreur %v, attendu *PolicyError", err)
+			}
+			if got := codes(policyErr.Violations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations %v, attendu %v", got, tt.want)
+			}
+		})
+	}
+}
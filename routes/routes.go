@@ -3,40 +3,197 @@
 package routes
 
 import (
+	"log"
+	"os"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/kdev1966/go-auth-api/controllers"
 	"github.com/kdev1966/go-auth-api/middleware"
+	"github.com/kdev1966/go-auth-api/utils"
 )
 
+// trustedProxies lit TRUSTED_PROXIES : adresses ou plages CIDR des proxies dont l'en-tête
+// X-Forwarded-For est cru, séparées par des virgules. Vide par défaut : aucun proxy n'est
+// cru et l'adresse du client est celle de la connexion.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func SetupRoutes() *gin.Engine {
 	router := gin.Default()
 
+	// c.ClientIP() sert de clé aux limites de débit et au blocage des échecs de connexion :
+	// sans proxy de confiance, un client ne peut pas la choisir via X-Forwarded-For
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES invalide:", err)
+	}
+
+	// Clés publiques de vérification des tokens
+	router.GET("/.well-known/jwks.json", controllers.JWKS)
+	router.GET("/.well-known/openid-configuration", controllers.OpenIDConfiguration)
+
+	// Limites de débit, ajustables via RATE_LIMIT_<NOM> (voir middleware.NewRateLimitPolicy)
+	loginLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("login", "10/1m", middleware.KeyByIP))
+	registerLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("register", "5/10m", middleware.KeyByIP))
+	refreshLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("refresh", "30/1m", middleware.KeyByIP))
+	emailLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("email", "5/15m", middleware.KeyByIP)) // emails envoyés
+	tokenLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("oauth_token", "60/1m", middleware.KeyByIP))
+	introspectLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("oauth_introspect", "600/1m", middleware.KeyByIP)) // passerelles d'API
+	apiLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("api", "300/1m", middleware.KeyByUser))
+	deviceLimit := middleware.RateLimit(middleware.NewRateLimitPolicy("device", "10/1m", middleware.KeyByUser)) // user_code devinables (RFC 8628, section 5.1)
+
 	// Routes publiques
 	public := router.Group("/api")
+	public.Use(apiLimit)
+	{
+		public.POST("/register", registerLimit, controllers.Register)
+		public.POST("/login", loginLimit, controllers.Login)
+		public.POST("/login/mfa", loginLimit, controllers.LoginMFA) // second facteur après le mot de passe
+		public.POST("/login/passkey/begin", loginLimit, controllers.BeginPasskeyLogin)
+		public.POST("/login/passkey/finish", loginLimit, controllers.FinishPasskeyLogin)
+		public.POST("/refresh", refreshLimit, controllers.RefreshToken)
+		public.POST("/verify-email", controllers.VerifyEmail)
+		public.POST("/verify-email/resend", emailLimit, controllers.ResendVerificationEmail)
+		public.POST("/password/forgot", emailLimit, controllers.ForgotPassword)
+		public.POST("/password/reset", loginLimit, controllers.ResetPassword)
+		public.POST("/invitations/decline", controllers.DeclineInvitation)
+	}
+
+	// Serveur d'autorisation OAuth 2.0 (code d'autorisation avec PKCE) et OpenID Connect
+	oauth := router.Group("/oauth")
 	{
-		public.POST("/register", controllers.Register)
-		public.POST("/login", controllers.Login)
-		public.POST("/refresh", controllers.RefreshToken)
+		// Appelée par la page du frontend (OIDC_AUTHORIZATION_URL) avec le token de l'utilisateur,
+		// pas par le navigateur redirigé depuis l'application
+		oauth.GET("/authorize", middleware.AuthMiddleware(), middleware.RequireUser(), middleware.RejectAPIKeys(), apiLimit, controllers.Authorize)
+		oauth.POST("/authorize", middleware.AuthMiddleware(), middleware.RequireUser(), middleware.RejectAPIKeys(), apiLimit, controllers.ApproveAuthorization)
+		oauth.POST("/device_authorization", tokenLimit, controllers.DeviceAuthorization)
+		oauth.POST("/token", tokenLimit, controllers.Token)
+		oauth.POST("/introspect", introspectLimit, controllers.IntrospectToken)
+		oauth.POST("/revoke", tokenLimit, controllers.RevokeToken)
+		oauth.GET("/userinfo", middleware.AuthMiddleware(), middleware.RequireUser(), apiLimit, controllers.UserInfo)
+		oauth.POST("/userinfo", middleware.AuthMiddleware(), middleware.RequireUser(), apiLimit, controllers.UserInfo)
+		oauth.GET("/logout", controllers.EndSession)
+		oauth.POST("/logout", controllers.EndSession)
 	}
 
-	// Routes protégées avec JWT
+	// Routes protégées avec JWT, pour le compte d'un utilisateur
 	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(), middleware.RequireUser(), apiLimit)
 	{
-		protected.GET("/me", controllers.GetMe)                         // accès au profil via l'ID du token
-		protected.GET("/users/:id", controllers.GetUserByID)            // admin ou user concerné
-		protected.PUT("/users/:id", controllers.UpdateUser)             // admin ou user concerné
-		protected.DELETE("/users/:id", controllers.DeleteUser)          // admin ou user concerné
-		protected.DELETE("/users/:id/hard", controllers.HardDeleteUser) // admin uniquement
-		protected.POST("/users/avatar", controllers.UploadAvatar)       // upload avatar
-		protected.GET("/logs", controllers.GetActivityLogs)
-
-		// Routes protégées par IsAdmin uniquement
-		admin := protected.Group("")
-		admin.Use(middleware.IsAdmin())
+		protected.GET("/me", middleware.RequireScope("profile:read"), controllers.GetMe)                        // accès au profil via l'ID du token
+		protected.GET("/users/:id", middleware.RequireScope("users:read"), controllers.GetUserByID)             // admin ou user concerné
+		protected.PUT("/users/:id", middleware.RequireScope("users:write"), controllers.UpdateUser)             // admin ou user concerné
+		protected.DELETE("/users/:id", middleware.RequireScope("users:write"), controllers.DeleteUser)          // admin ou user concerné
+		protected.DELETE("/users/:id/hard", middleware.RequireScope("users:write"), controllers.HardDeleteUser) // admin uniquement
+		protected.POST("/users/avatar", middleware.RequireScope("users:write"), controllers.UploadAvatar)       // upload avatar
+		protected.GET("/logs", middleware.RequireScope("logs:read"), controllers.GetActivityLogs)
+
+		// Gestion du compte : réservée aux sessions interactives, pas aux clés d'API
+		account := protected.Group("")
+		account.Use(middleware.RejectAPIKeys())
+		{
+			account.GET("/me/sessions", controllers.GetMySessions) // sessions ouvertes de l'utilisateur
+			account.DELETE("/me/sessions/:id", controllers.DeleteMySession)
+			account.POST("/me/2fa/enroll", controllers.EnrollTOTP) // double authentification TOTP
+			account.POST("/me/2fa/confirm", controllers.ConfirmTOTP)
+			account.POST("/me/2fa/disable", controllers.DisableTOTP)
+			account.POST("/me/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+			account.GET("/me/passkeys", controllers.GetMyPasskeys) // passkeys WebAuthn
+			account.DELETE("/me/passkeys/:id", controllers.DeleteMyPasskey)
+			account.POST("/me/passkeys/register/begin", controllers.BeginPasskeyRegistration)
+			account.POST("/me/passkeys/register/finish", controllers.FinishPasskeyRegistration)
+			account.GET("/me/api-keys", controllers.GetMyAPIKeys) // tokens d'accès personnels
+			account.POST("/me/api-keys", controllers.CreateMyAPIKey)
+			account.DELETE("/me/api-keys/:id", controllers.DeleteMyAPIKey)
+			account.POST("/invitations/accept", controllers.AcceptInvitation)
+			account.GET("/me/oauth/consents", controllers.GetMyOAuthConsents) // applications autorisées
+			account.DELETE("/me/oauth/consents/:id", controllers.DeleteMyOAuthConsent)
+			account.GET("/device", deviceLimit, controllers.GetDeviceRequest) // demande d'un appareil, désignée par ?user_code=
+			account.POST("/device/approve", deviceLimit, controllers.ApproveDevice)
+			account.POST("/logout", controllers.Logout)        // révoque le token courant
+			account.POST("/logout/all", controllers.LogoutAll) // révoque tous les tokens de l'utilisateur
+		}
+
+		// Routes d'administration, chacune protégée par sa permission
+		protected.GET("/users", middleware.RequireScope("users:read"), middleware.RequirePermission(utils.PermUsersRead), controllers.GetAllUsers)
+		protected.PATCH("/users/:id/restore", middleware.RequireScope("users:write"), middleware.RequirePermission(utils.PermUsersRestore), controllers.RestoreUser)
+		protected.PATCH("/users/:id/unlock", middleware.RequireScope("users:write"), middleware.RequirePermission(utils.PermUsersUnlock), controllers.UnlockUser)
+		protected.GET("/users/:id/sessions", middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermSessionsAdmin), controllers.GetUserSessions)
+		protected.DELETE("/users/:id/sessions/:session_id", middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermSessionsAdmin), controllers.DeleteUserSession)
+		protected.GET("/admin/keys", middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermKeysManage), controllers.GetSigningKeys)
+		protected.POST("/admin/keys/rotate", middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermKeysManage), controllers.RotateSigningKey)
+
+		// Organisations (tenants), leurs membres et l'organisation active de la session
+		orgs := protected.Group("/orgs")
+		orgs.Use(middleware.RejectAPIKeys())
+		{
+			orgs.GET("", controllers.GetOrganizations)
+			orgs.POST("", controllers.CreateOrganization)
+			orgs.GET("/:id", controllers.GetOrganization)
+			orgs.PUT("/:id", controllers.UpdateOrganization)
+			orgs.DELETE("/:id", controllers.DeleteOrganization)
+			orgs.POST("/:id/switch", controllers.SwitchOrganization)
+			orgs.GET("/:id/members", controllers.GetOrganizationMembers)
+			orgs.POST("/:id/members", controllers.AddOrganizationMember)
+			orgs.PUT("/:id/members/:user_id", controllers.UpdateOrganizationMember)
+			orgs.DELETE("/:id/members/:user_id", controllers.RemoveOrganizationMember)
+			orgs.GET("/:id/invitations", controllers.GetInvitations)
+			orgs.POST("/:id/invitations", emailLimit, controllers.CreateInvitation)
+			orgs.POST("/:id/invitations/:invitation_id/resend", emailLimit, controllers.ResendInvitation)
+			orgs.DELETE("/:id/invitations/:invitation_id", controllers.RevokeInvitation)
+		}
+
+		// Clients OAuth
+		clients := protected.Group("/oauth/clients")
+		clients.Use(middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermClientsManage))
+		{
+			clients.GET("", controllers.GetOAuthClients)
+			clients.POST("", controllers.CreateOAuthClient)
+			clients.GET("/:id", controllers.GetOAuthClient)
+			clients.PUT("/:id", controllers.UpdateOAuthClient)
+			clients.DELETE("/:id", controllers.DeleteOAuthClient)
+			clients.POST("/:id/secret", controllers.RotateOAuthClientSecret)
+		}
+
+		// Clients de service (grant client_credentials)
+		services := protected.Group("/service-clients")
+		services.Use(middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermClientsManage))
+		{
+			services.GET("", controllers.GetServiceClients)
+			services.POST("", controllers.CreateServiceClient)
+			services.GET("/:id", controllers.GetServiceClient)
+			services.PUT("/:id", controllers.UpdateServiceClient)
+			services.DELETE("/:id", controllers.DeleteServiceClient)
+			services.POST("/:id/secret", controllers.RotateServiceClientSecret)
+		}
+
+		// Rôles, permissions et groupes
+		roles := protected.Group("")
+		roles.Use(middleware.RejectAPIKeys(), middleware.RequirePermission(utils.PermRolesManage))
 		{
-			admin.GET("/users", controllers.GetAllUsers)
-			admin.PATCH("/users/:id/restore", controllers.RestoreUser)
+			roles.GET("/permissions", controllers.GetPermissions)
+			roles.GET("/roles", controllers.GetRoles)
+			roles.POST("/roles", controllers.CreateRole)
+			roles.PUT("/roles/:id", controllers.UpdateRole)
+			roles.DELETE("/roles/:id", controllers.DeleteRole)
+			roles.PUT("/users/:id/role", controllers.SetUserRole) // rôle principal, journalisé avec l'auteur
+			roles.GET("/users/:id/roles", controllers.GetUserRoles)
+			roles.POST("/users/:id/roles", controllers.AssignUserRole)
+			roles.DELETE("/users/:id/roles/:role_id", controllers.RemoveUserRole)
+			roles.GET("/groups", controllers.GetGroups) // groupes d'utilisateurs, imbricables
+			roles.POST("/groups", controllers.CreateGroup)
+			roles.GET("/groups/:id", controllers.GetGroup)
+			roles.PUT("/groups/:id", controllers.UpdateGroup)
+			roles.DELETE("/groups/:id", controllers.DeleteGroup)
+			roles.POST("/groups/:id/members", controllers.AddGroupMember)
+			roles.DELETE("/groups/:id/members/:user_id", controllers.RemoveGroupMember)
 		}
 	}
 
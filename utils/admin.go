@@ -0,0 +1,112 @@
+// utils/admin.go
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/passwords"
+	"gorm.io/gorm"
+)
+
+// ErrAdminEmailTaken : l'email demandé pour un nouvel administrateur appartient déjà à un compte.
+var ErrAdminEmailTaken = errors.New("un compte existe déjà avec cette adresse email")
+
+// CreateAdmin crée un compte administrateur. L'adresse d'un compte créé ainsi est considérée
+// comme vérifiée : c'est l'exploitant qui la fournit. Un compte existant portant cet email
+// n'est jamais repris (ErrAdminEmailTaken) : sa promotion passe par PromoteAdmin.
+func CreateAdmin(username, email, password string) (*models.User, error) {
+	var user models.User
+	err := config.DB.Where("email = ?", email).First(&user).Error
+	if err == nil {
+		return nil, ErrAdminEmailTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := passwords.Validate(password, username, email); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user = models.User{
+		Username:        username,
+		Email:           email,
+		Password:        hashedPassword,
+		Role:            RoleAdmin,
+		EmailVerifiedAt: &now,
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	if _, err := AssignRole(user.ID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	LogActivity(user.ID, "admin_bootstrap", "Compte administrateur créé depuis le serveur")
+	return &user, nil
+}
+
+// PromoteAdmin donne le rôle principal admin au compte existant portant cet email. Réservé à
+// la commande create-admin --promote : l'exploitant désigne explicitement le compte.
+func PromoteAdmin(email string) (*models.User, error) {
+	var user models.User
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if _, err := SetPrimaryRole(user.ID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	user.Role = RoleAdmin
+	LogActivity(user.ID, "admin_bootstrap", "Compte promu administrateur depuis le serveur")
+	return &user, nil
+}
+
+// HasAdmin indique si au moins un utilisateur possède le rôle admin.
+func HasAdmin() (bool, error) {
+	var count int64
+	err := config.DB.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ?", RoleAdmin).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SeedAdminFromEnv crée le premier administrateur à partir de ADMIN_EMAIL, ADMIN_USERNAME
+// (défaut "admin") et ADMIN_PASSWORD, uniquement s'il n'existe encore aucun administrateur.
+// Sans ADMIN_EMAIL, rien n'est fait : utiliser alors la commande create-admin. Un compte
+// existant portant ADMIN_EMAIL n'est pas promu : n'importe qui a pu l'inscrire.
+func SeedAdminFromEnv() error {
+	email := os.Getenv("ADMIN_EMAIL")
+	if email == "" {
+		return nil
+	}
+	exists, err := HasAdmin()
+	if err != nil || exists {
+		return err
+	}
+
+	username := os.Getenv("ADMIN_USERNAME")
+	if username == "" {
+		username = "admin"
+	}
+	user, err := CreateAdmin(username, email, os.Getenv("ADMIN_PASSWORD"))
+	if errors.Is(err, ErrAdminEmailTaken) {
+		return fmt.Errorf("ADMIN_EMAIL=%s : %w ; pour le promouvoir, utiliser go-auth-api create-admin --promote %s", email, err, email)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Premier administrateur créé : %s (id=%d)", user.Email, user.ID)
+	return nil
+}
@@ -0,0 +1,101 @@
+// utils/api_key.go
+
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// APIKeyPrefix distingue les clés d'API des JWT dans l'en-tête Authorization.
+const APIKeyPrefix = "pat_"
+
+// apiScopes sont les scopes des routes de l'API, accordés aux clés d'API et aux clients OAuth.
+var apiScopes = []string{"profile:read", "users:read", "users:write", "logs:read"}
+
+// Scopes qu'une clé d'API peut recevoir : ceux des routes de l'API, et ceux de
+// /oauth/introspect et /oauth/revoke. Une clé sans scope a tous les droits de son
+// propriétaire, hors routes réservées aux sessions interactives.
+var APIKeyScopes = append(append([]string{}, apiScopes...), ScopeTokensIntrospect, ScopeTokensRevoke)
+
+var ErrInvalidAPIKey = errors.New("clé d'API invalide, expirée ou révoquée")
+
+// IsAPIKey indique si un token présenté est une clé d'API plutôt qu'un JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// ValidAPIKeyScope indique si scope fait partie de APIKeyScopes.
+func ValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey génère une clé d'API. La clé en clair n'est retournée qu'ici :
+// elle est de la forme pat_<identifiant>_<secret> et seule son empreinte est conservée.
+func CreateAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	prefix := APIKeyPrefix + hex.EncodeToString(id)
+	rawKey := prefix + "_" + hex.EncodeToString(secret)
+
+	key := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   HashToken(rawKey),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	if err := config.DB.Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+	return &key, rawKey, nil
+}
+
+// AuthenticateAPIKey vérifie une clé d'API et retourne la clé et son propriétaire.
+// Chaque utilisation met à jour last_used_at.
+func AuthenticateAPIKey(rawKey string) (*models.APIKey, *models.User, error) {
+	var key models.APIKey
+	if err := config.DB.Where("key_hash = ?", HashToken(rawKey)).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && key.ExpiresAt.Before(now)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, key.UserID).Error; err != nil || user.DeletedAt != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if err := config.DB.Model(&key).Update("last_used_at", now).Error; err != nil {
+		return nil, nil, err
+	}
+	return &key, &user, nil
+}
+
+// APIKeyScopeList retourne les scopes d'une clé sous forme de liste (nil = tous).
+func APIKeyScopeList(key *models.APIKey) []string {
+	if key.Scopes == "" {
+		return nil
+	}
+	return strings.Fields(key.Scopes)
+}
@@ -0,0 +1,200 @@
+// utils/device.go
+
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// OAuthDeviceCodeGrantType est le grant_type avec lequel un appareil échange son device_code.
+const OAuthDeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Codes d'erreur propres au grant device_code (RFC 8628, section 3.5).
+const (
+	OAuthAuthorizationPending = "authorization_pending"
+	OAuthSlowDown             = "slow_down"
+	OAuthExpiredToken         = "expired_token"
+)
+
+const (
+	// DeviceCodeTTL est la durée laissée à l'utilisateur pour saisir le user_code.
+	DeviceCodeTTL = 10 * time.Minute
+	// DevicePollInterval est le délai initial entre deux interrogations, en secondes ;
+	// chaque slow_down l'allonge de 5 secondes.
+	DevicePollInterval = 5
+)
+
+// userCodeAlphabet : consonnes majuscules, sans voyelles ni caractères ambigus (RFC 8628,
+// section 6.1) ; 20^8 combinaisons.
+const (
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// ErrInvalidUserCode : code saisi inconnu, expiré ou déjà utilisé.
+var ErrInvalidUserCode = errors.New("code invalide, expiré ou déjà utilisé")
+
+func newUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode met en forme un user_code saisi par l'utilisateur : majuscules, sans
+// tiret ni espace.
+func NormalizeUserCode(input string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(input)))
+}
+
+// FormatUserCode présente un user_code en deux groupes : "BCDF-GHJK".
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// CreateDeviceCode enregistre une demande d'autorisation d'appareil et retourne le
+// device_code, qui n'est retourné qu'ici.
+func CreateDeviceCode(client *models.OAuthClient, scope string) (*models.OAuthDeviceCode, string, error) {
+	deviceCode, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	record := models.OAuthDeviceCode{
+		DeviceCodeHash: HashToken(deviceCode),
+		ClientID:       client.ClientID,
+		Scope:          scope,
+		PollInterval:   DevicePollInterval,
+		ExpiresAt:      time.Now().Add(DeviceCodeTTL),
+	}
+	// Un user_code déjà attribué fait échouer l'insertion : on en tire un autre
+	for attempt := 0; ; attempt++ {
+		if record.UserCode, err = newUserCode(); err != nil {
+			return nil, "", err
+		}
+		if err = config.DB.Create(&record).Error; err == nil {
+			return &record, deviceCode, nil
+		}
+		if attempt == 2 {
+			return nil, "", err
+		}
+	}
+}
+
+// FindPendingDeviceCode charge la demande en attente désignée par un user_code saisi.
+func FindPendingDeviceCode(userCode string) (*models.OAuthDeviceCode, error) {
+	var record models.OAuthDeviceCode
+	err := config.DB.Where("user_code = ? AND approved_at IS NULL AND denied_at IS NULL AND expires_at > ?", NormalizeUserCode(userCode), time.Now()).
+		First(&record).Error
+	if err != nil {
+		return nil, ErrInvalidUserCode
+	}
+	return &record, nil
+}
+
+// AnswerDeviceCode enregistre la réponse de l'utilisateur à une demande d'appareil, avec
+// l'authentification de sa session en cas d'accord. Une demande ne reçoit qu'une réponse.
+func AnswerDeviceCode(record *models.OAuthDeviceCode, userID uint, approve bool, authTime time.Time, authMethods string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"user_id": userID}
+	if approve {
+		updates["approved_at"] = now
+		updates["auth_time"] = authTime
+		updates["auth_methods"] = authMethods
+	} else {
+		updates["denied_at"] = now
+	}
+	result := config.DB.Model(&models.OAuthDeviceCode{}).
+		Where("id = ? AND approved_at IS NULL AND denied_at IS NULL AND expires_at > ?", record.ID, now).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidUserCode
+	}
+	return nil
+}
+
+// PollDeviceCode traite une interrogation de /oauth/token par l'appareil (RFC 8628,
+// section 3.4). Tant que l'utilisateur n'a pas répondu, retourne authorization_pending ;
+// une interrogation plus rapprochée que l'intervalle reçoit slow_down et l'allonge de
+// 5 secondes. Retourne la demande accordée, consommée, lorsque l'utilisateur l'a approuvée.
+func PollDeviceCode(client *models.OAuthClient, deviceCode string) (*models.OAuthDeviceCode, error) {
+	var record models.OAuthDeviceCode
+	if err := config.DB.Where("device_code_hash = ?", HashToken(deviceCode)).First(&record).Error; err != nil {
+		return nil, oauthError(OAuthInvalidGrant, "device_code invalide")
+	}
+	if record.ClientID != client.ClientID {
+		return nil, oauthError(OAuthInvalidGrant, "device_code invalide")
+	}
+	if record.UsedAt != nil {
+		return nil, oauthError(OAuthInvalidGrant, "device_code déjà utilisé")
+	}
+	now := time.Now()
+	if record.ExpiresAt.Before(now) {
+		return nil, oauthError(OAuthExpiredToken, "device_code expiré, recommencez l'autorisation")
+	}
+
+	// L'interrogation n'est comptée que si aucune autre n'a eu lieu entre-temps
+	interval := record.PollInterval
+	tooSoon := record.LastPolledAt != nil && now.Sub(*record.LastPolledAt) < time.Duration(interval)*time.Second
+	if tooSoon {
+		interval += 5
+	}
+	result := config.DB.Model(&models.OAuthDeviceCode{}).
+		Where("id = ? AND poll_count = ?", record.ID, record.PollCount).
+		Updates(map[string]interface{}{"poll_count": record.PollCount + 1, "poll_interval": interval, "last_polled_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if tooSoon || result.RowsAffected == 0 {
+		return nil, oauthError(OAuthSlowDown, "interrogations trop fréquentes, espacez-les d'au moins "+strconv.Itoa(interval)+" secondes")
+	}
+
+	switch {
+	case record.DeniedAt != nil:
+		return nil, oauthError(OAuthAccessDenied, "l'utilisateur a refusé l'autorisation")
+	case record.ApprovedAt == nil:
+		return nil, oauthError(OAuthAuthorizationPending, "en attente de l'accord de l'utilisateur")
+	}
+
+	result = config.DB.Model(&models.OAuthDeviceCode{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, oauthError(OAuthInvalidGrant, "device_code déjà utilisé")
+	}
+	return &record, nil
+}
+
+// PurgeExpiredDeviceCodes supprime les demandes d'appareil expirées.
+func PurgeExpiredDeviceCodes() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthDeviceCode{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,95 @@
+// utils/email_verification.go
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/mailer"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm/clause"
+)
+
+// EmailVerificationTokenTTL est la durée de validité d'un lien de vérification.
+const EmailVerificationTokenTTL = 24 * time.Hour
+
+var ErrInvalidVerificationToken = errors.New("token de vérification invalide ou déjà utilisé")
+
+// RequireEmailVerification indique si Login refuse les comptes dont l'email n'est pas vérifié
+// (REQUIRE_EMAIL_VERIFICATION=true).
+func RequireEmailVerification() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))
+	return required
+}
+
+// IssueEmailVerificationToken signe un token de vérification lié à l'adresse actuelle de l'utilisateur.
+// Si l'adresse change, les tokens déjà envoyés deviennent invalides.
+func IssueEmailVerificationToken(user *models.User) (string, error) {
+	claims := newClaims(strconv.FormatUint(uint64(user.ID), 10), TokenTypeEmailVerify, NewTokenID(), time.Now().Add(EmailVerificationTokenTTL))
+	claims.Email = user.Email
+	return SignToken(claims)
+}
+
+// SendVerificationEmail envoie un lien de vérification à l'adresse de l'utilisateur.
+// Le lien pointe vers EMAIL_VERIFICATION_URL (page du front qui appelle POST /api/verify-email).
+func SendVerificationEmail(user *models.User) error {
+	token, err := IssueEmailVerificationToken(user)
+	if err != nil {
+		return err
+	}
+
+	var body string
+	if url := os.Getenv("EMAIL_VERIFICATION_URL"); url != "" {
+		body = fmt.Sprintf("Bonjour %s,\n\nConfirmez votre adresse email en ouvrant ce lien :\n%s?token=%s\n\nCe lien expire dans %s.\n",
+			user.Username, url, token, EmailVerificationTokenTTL)
+	} else {
+		body = fmt.Sprintf("Bonjour %s,\n\nConfirmez votre adresse email avec ce token :\n%s\n\nCe token expire dans %s.\n",
+			user.Username, token, EmailVerificationTokenTTL)
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Vérifiez votre adresse email",
+		Body:    body,
+	})
+}
+
+// VerifyEmail consomme un token de vérification et marque l'adresse comme vérifiée.
+// Un token ne peut servir qu'une fois.
+func VerifyEmail(tokenString string) (*models.User, error) {
+	claims, err := ParseClaims(tokenString, TokenTypeEmailVerify)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID()).Error; err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	if user.Email != claims.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	// Le jti est révoqué à l'usage : seul le premier appel l'insère
+	used := models.RevokedToken{JTI: claims.ID, UserID: user.ID, ExpiresAt: claims.ExpiresAt.Time}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&used)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
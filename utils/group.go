@@ -0,0 +1,67 @@
+// utils/group.go
+
+package utils
+
+import (
+	"errors"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrGroupCycle = errors.New("un groupe ne peut pas être son propre ancêtre")
+
+// userGroupsCTE liste récursivement les groupes d'un utilisateur : ses groupes directs puis
+// tous leurs ancêtres. UNION (et non UNION ALL) arrête la récursion sur un groupe déjà vu.
+const userGroupsCTE = `WITH RECURSIVE member_groups(id) AS (
+	SELECT group_id FROM group_members WHERE user_id = ?
+	UNION
+	SELECT groups.parent_id FROM groups
+	JOIN member_groups ON groups.id = member_groups.id
+	WHERE groups.parent_id IS NOT NULL
+)`
+
+// UserGroupIDs retourne les IDs des groupes dont l'utilisateur est membre, directement
+// ou par un sous-groupe.
+func UserGroupIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := config.DB.Raw(userGroupsCTE+` SELECT id FROM member_groups`, userID).Scan(&ids).Error
+	return ids, err
+}
+
+// CheckGroupParent vérifie que parentID peut devenir le parent de groupID sans créer de cycle.
+func CheckGroupParent(groupID, parentID uint) error {
+	for id := parentID; id != 0; {
+		if id == groupID {
+			return ErrGroupCycle
+		}
+		var parent models.Group
+		if err := config.DB.Select("id", "parent_id").First(&parent, id).Error; err != nil {
+			return err
+		}
+		if parent.ParentID == nil {
+			break
+		}
+		id = *parent.ParentID
+	}
+	return nil
+}
+
+// DeleteGroup supprime un groupe, ses membres et ses rôles. Ses sous-groupes sont
+// rattachés à son propre parent.
+func DeleteGroup(group *models.Group) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Group{}).Where("parent_id = ?", group.ID).
+			Update("parent_id", group.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Members").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+}
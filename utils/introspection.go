@@ -0,0 +1,192 @@
+// utils/introspection.go
+
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// Scopes de /oauth/introspect et /oauth/revoke. tokens:introspect permet à un client de
+// service (ex. une passerelle d'API) d'introspecter les tokens de tous les utilisateurs, et
+// non les seuls siens. Une clé d'API restreinte doit avoir le scope de l'endpoint appelé.
+const (
+	ScopeTokensIntrospect = "tokens:introspect"
+	ScopeTokensRevoke     = "tokens:revoke"
+)
+
+var (
+	ErrTokenRevoked  = errors.New("token révoqué")
+	ErrSessionClosed = errors.New("session fermée")
+)
+
+// ValidateAccessToken vérifie un token d'accès : signature, expiration, type, puis son état
+// de révocation (jti, logout global, session fermée ou client de service désactivé).
+func ValidateAccessToken(tokenString string) (*Claims, error) {
+	claims, err := ParseClaims(tokenString, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if serviceClientID := claims.ServiceClientID(); serviceClientID != "" {
+		if IsServiceTokenRevoked(claims.ID, serviceClientID) {
+			return nil, ErrTokenRevoked
+		}
+		return claims, nil
+	}
+
+	if IsTokenRevoked(claims.ID, claims.UserID(), claims.IssuedAtUnix()) {
+		return nil, ErrTokenRevoked
+	}
+	// Un token rattaché à une session fermée n'est plus accepté
+	if claims.SessionID != 0 && !IsSessionActive(claims.SessionID) {
+		return nil, ErrSessionClosed
+	}
+	return claims, nil
+}
+
+// ValidateRefreshToken vérifie un refresh token et retourne ses claims et son enregistrement.
+// Un token déjà utilisé (retiré par une rotation) reste retourné : c'est à l'appelant de
+// décider s'il s'agit d'une réutilisation.
+func ValidateRefreshToken(tokenString string) (*Claims, *models.RefreshToken, error) {
+	claims, err := ParseRefreshToken(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+	userID := claims.UserID()
+	if claims.ID == "" || IsTokenRevoked(claims.ID, userID, claims.IssuedAtUnix()) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var record models.RefreshToken
+	if err := config.DB.Where("jti = ? AND user_id = ?", claims.ID, userID).First(&record).Error; err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if record.RevokedAt != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	return claims, &record, nil
+}
+
+// TokenInfo décrit un token d'accès ou un refresh token actif.
+type TokenInfo struct {
+	Claims   *Claims
+	Refresh  *models.RefreshToken // nil pour un token d'accès
+	Session  *models.Session      // session du refresh token
+	UserID   uint                 // 0 pour un token de service
+	ClientID string               // client OAuth ou de service, vide pour une connexion directe
+}
+
+// InspectToken retrouve un token actif, d'accès ou de rafraîchissement. hint
+// ("access_token" ou "refresh_token") indique le type à essayer en premier (RFC 7009,
+// section 2.1) ; l'autre est essayé ensuite. Retourne ErrInvalidToken si le token est
+// invalide, expiré, révoqué ou déjà utilisé.
+func InspectToken(tokenString, hint string) (*TokenInfo, error) {
+	inspectors := []func(string) (*TokenInfo, error){inspectAccessToken, inspectRefreshToken}
+	if hint == "refresh_token" {
+		inspectors[0], inspectors[1] = inspectors[1], inspectors[0]
+	}
+	for _, inspect := range inspectors {
+		if info, err := inspect(tokenString); err == nil {
+			return info, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+func inspectAccessToken(tokenString string) (*TokenInfo, error) {
+	claims, err := ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenInfo{Claims: claims, UserID: claims.UserID(), ClientID: claims.ClientID}, nil
+}
+
+func inspectRefreshToken(tokenString string) (*TokenInfo, error) {
+	claims, record, err := ValidateRefreshToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if record.UsedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	var session models.Session
+	err = config.DB.Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", record.FamilyID, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return &TokenInfo{Claims: claims, Refresh: record, Session: &session, UserID: record.UserID, ClientID: session.ClientID}, nil
+}
+
+// TokenIntrospection est la réponse d'introspection d'un token (RFC 7662, section 2.2).
+// Un token inactif ne renvoie que "active": false.
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+	OrgID     uint   `json:"org_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"` // "Bearer" pour un token d'accès, "refresh_token" sinon
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// Introspect décrit un token actif. Un token d'accès est décrit par ses claims, tels que
+// AuthMiddleware les utilise ; un refresh token par sa session et l'utilisateur actuel.
+func Introspect(info *TokenInfo) *TokenIntrospection {
+	claims := info.Claims
+	result := &TokenIntrospection{
+		Active:   true,
+		Subject:  claims.Subject,
+		ClientID: info.ClientID,
+		IssuedAt: claims.IssuedAtUnix(),
+		Issuer:   claims.Issuer,
+		JTI:      claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+
+	if info.Refresh == nil {
+		result.TokenType = "Bearer"
+		result.Scope = strings.Join(claims.Scopes, " ")
+		result.Username = claims.Username
+		result.Role = claims.Role
+		result.OrgID = claims.OrgID
+		return result
+	}
+
+	result.TokenType = "refresh_token"
+	result.Scope = info.Session.Scope
+	if info.Session.OrganizationID != nil {
+		result.OrgID = *info.Session.OrganizationID
+	}
+	var user models.User
+	if err := config.DB.First(&user, info.UserID).Error; err == nil {
+		result.Username = user.Username
+		result.Role = user.Role
+	}
+	return result
+}
+
+// RevokeInspectedToken révoque un token actif. Révoquer un refresh token ferme sa session,
+// et donc les tokens d'accès qui y sont rattachés (RFC 7009, section 2.1).
+func RevokeInspectedToken(info *TokenInfo) error {
+	if info.Refresh != nil {
+		return RevokeRefreshTokenFamily(info.Refresh.FamilyID)
+	}
+	var expiresAt time.Time
+	if info.Claims.ExpiresAt != nil {
+		expiresAt = info.Claims.ExpiresAt.Time
+	}
+	return RevokeToken(info.Claims.ID, info.UserID, expiresAt)
+}
@@ -0,0 +1,199 @@
+// utils/invitation.go
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/mailer"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL est la durée de validité d'une invitation, prolongée à chaque renvoi.
+const InvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidInvitation       = errors.New("invitation invalide, expirée ou déjà utilisée")
+	ErrInvitationPending       = errors.New("une invitation est déjà en attente pour cette adresse")
+	ErrInvitationEmailMismatch = errors.New("l'invitation a été envoyée à une autre adresse")
+)
+
+// CreateInvitation enregistre une invitation à rejoindre une organisation avec un rôle
+// (par défaut user). Le lien est envoyé séparément par SendInvitationEmail.
+func CreateInvitation(orgID uint, email, roleName string, invitedByID uint) (*models.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if roleName == "" {
+		roleName = RoleUser
+	}
+	if _, err := findRolesByName(config.DB, []string{roleName}); err != nil {
+		return nil, err
+	}
+
+	var member int64
+	if err := config.DB.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND LOWER(users.email) = ?", orgID, email).
+		Count(&member).Error; err != nil {
+		return nil, err
+	}
+	if member > 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	var pending int64
+	if err := config.DB.Model(&models.Invitation{}).
+		Where("organization_id = ? AND email = ? AND status = ? AND expires_at > ?", orgID, email, models.InvitationPending, time.Now()).
+		Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, ErrInvitationPending
+	}
+
+	invitation := models.Invitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           roleName,
+		InvitedByID:    invitedByID,
+		TokenID:        NewTokenID(),
+		Status:         models.InvitationPending,
+		ExpiresAt:      time.Now().Add(InvitationTTL),
+	}
+	if err := config.DB.Create(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// SendInvitationEmail envoie (ou renvoie) le lien d'une invitation en attente. Chaque envoi
+// signe un nouveau token et prolonge l'invitation : les liens envoyés auparavant ne sont plus valides.
+// Le lien pointe vers INVITATION_URL (page du front qui appelle /api/invitations/accept ou /api/register).
+func SendInvitationEmail(invitation *models.Invitation, org *models.Organization) error {
+	if invitation.Status != models.InvitationPending {
+		return ErrInvalidInvitation
+	}
+
+	jti := NewTokenID()
+	expiresAt := time.Now().Add(InvitationTTL)
+	claims := newClaims(strconv.FormatUint(uint64(invitation.ID), 10), TokenTypeInvitation, jti, expiresAt)
+	claims.Email = invitation.Email
+	token, err := SignToken(claims)
+	if err != nil {
+		return err
+	}
+	if err := config.DB.Model(invitation).Updates(map[string]interface{}{"token_id": jti, "expires_at": expiresAt}).Error; err != nil {
+		return err
+	}
+
+	var body string
+	if url := os.Getenv("INVITATION_URL"); url != "" {
+		body = fmt.Sprintf("Bonjour,\n\nVous êtes invité à rejoindre l'organisation %s. Pour accepter, ouvrez ce lien :\n%s?token=%s\n\nCette invitation expire dans %s.\n",
+			org.Name, url, token, InvitationTTL)
+	} else {
+		body = fmt.Sprintf("Bonjour,\n\nVous êtes invité à rejoindre l'organisation %s. Pour accepter, utilisez ce token :\n%s\n\nCette invitation expire dans %s.\n",
+			org.Name, token, InvitationTTL)
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: "Invitation à rejoindre " + org.Name,
+		Body:    body,
+	})
+}
+
+// FindInvitationByToken vérifie le lien d'une invitation et retourne l'invitation
+// correspondante si elle est toujours en attente.
+func FindInvitationByToken(tokenString string) (*models.Invitation, error) {
+	claims, err := ParseClaims(tokenString, TokenTypeInvitation)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	var invitation models.Invitation
+	if err := config.DB.Preload("Organization").
+		Where("id = ? AND token_id = ?", claims.UserID(), claims.ID).
+		First(&invitation).Error; err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.Status != models.InvitationPending || invitation.ExpiresAt.Before(time.Now()) || invitation.Email != claims.Email {
+		return nil, ErrInvalidInvitation
+	}
+	return &invitation, nil
+}
+
+// respondInvitation fait passer une invitation en attente à un nouveau statut.
+// Seul le premier appel réussit, ce qui rend chaque lien à usage unique.
+func respondInvitation(tx *gorm.DB, invitation *models.Invitation, status string, acceptedByID *uint) error {
+	now := time.Now()
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": now, "accepted_by_id": acceptedByID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidInvitation
+	}
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	invitation.AcceptedByID = acceptedByID
+	return nil
+}
+
+// AcceptInvitation fait entrer l'utilisateur dans l'organisation avec le rôle de l'invitation.
+// L'adresse du compte doit être celle qui a reçu l'invitation. L'invitation n'est marquée
+// acceptée que si l'adhésion est enregistrée.
+func AcceptInvitation(invitation *models.Invitation, user *models.User) error {
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return ErrInvitationEmailMismatch
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := respondInvitation(tx, invitation, models.InvitationAccepted, &user.ID); err != nil {
+			return err
+		}
+		if _, err := addMember(tx, invitation.OrganizationID, user.ID, []string{invitation.Role}); err != nil && err != ErrAlreadyMember {
+			return err
+		}
+		return nil
+	})
+}
+
+// DeclineInvitation refuse une invitation.
+func DeclineInvitation(invitation *models.Invitation) error {
+	return respondInvitation(config.DB, invitation, models.InvitationDeclined, nil)
+}
+
+// RevokeInvitation annule une invitation en attente.
+func RevokeInvitation(invitation *models.Invitation) error {
+	return respondInvitation(config.DB, invitation, models.InvitationRevoked, nil)
+}
+
+// AcceptPendingInvitations accepte les invitations en attente adressées à l'email d'un
+// utilisateur. À n'appeler qu'une fois l'adresse prouvée (email vérifié).
+func AcceptPendingInvitations(user *models.User) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	if err := config.DB.
+		Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), models.InvitationPending, time.Now()).
+		Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+
+	var accepted []models.Invitation
+	for i := range invitations {
+		if err := AcceptInvitation(&invitations[i], user); err != nil {
+			if err == ErrInvalidInvitation {
+				continue
+			}
+			return accepted, err
+		}
+		accepted = append(accepted, invitations[i])
+	}
+	return accepted, nil
+}
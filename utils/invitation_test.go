@@ -0,0 +1,72 @@
+// utils/invitation_test.go
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+func TestAcceptInvitation(t *testing.T) {
+	openTestDB(t)
+	db := config.DB
+	if err := db.AutoMigrate(&models.Invitation{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Create(&models.Role{Name: RoleUser}).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: RoleUser}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	org := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		role       string
+		wantErr    bool
+		wantStatus string
+		wantMember bool
+	}{
+		// L'adhésion échoue : l'invitation doit rester en attente
+		{"rôle inexistant", "inexistant", true, models.InvitationPending, false},
+		{"rôle valide", RoleUser, false, models.InvitationAccepted, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invitation := models.Invitation{
+				OrganizationID: org.ID,
+				Email:          user.Email,
+				Role:           tt.role,
+				InvitedByID:    user.ID,
+				TokenID:        tt.name,
+				Status:         models.InvitationPending,
+				ExpiresAt:      time.Now().Add(InvitationTTL),
+			}
+			if err := db.Create(&invitation).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			err := AcceptInvitation(&invitation, &user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AcceptInvitation: %v", err)
+			}
+			var stored models.Invitation
+			db.First(&stored, invitation.ID)
+			if stored.Status != tt.wantStatus {
+				t.Errorf("statut %q, attendu %q", stored.Status, tt.wantStatus)
+			}
+			if IsMember(org.ID, user.ID) != tt.wantMember {
+				t.Errorf("membre : %v, attendu %v", !tt.wantMember, tt.wantMember)
+			}
+		})
+	}
+}
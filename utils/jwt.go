@@ -3,31 +3,169 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kdev1966/go-auth-api/models"
 )
 
-func GenerateToken(userID uint, duration time.Duration) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(duration).Unix(),
-		"iss":     "go-auth-api",
+// AccessTokenTTL est la durée de vie d'un token d'accès.
+const AccessTokenTTL = 15 * time.Minute
+
+// Types de tokens émis par l'API (claim "type").
+const (
+	TokenTypeAccess      = "access"
+	TokenTypeRefresh     = "refresh"
+	TokenTypeMFAPending  = "mfa_pending" // mot de passe vérifié, second facteur attendu
+	TokenTypeEmailVerify = "email_verification"
+	TokenTypeInvitation  = "org_invitation" // sub = ID de l'invitation
+)
+
+// ErrInvalidToken est retournée pour tout token mal signé, expiré ou du mauvais type.
+var ErrInvalidToken = errors.New("token invalide")
+
+// Claims sont les claims de tous les tokens émis par l'API.
+// Le sujet (sub) est l'ID de l'utilisateur.
+type Claims struct {
+	Username  string                 `json:"username,omitempty"`
+	Email     string                 `json:"email,omitempty"` // adresse à vérifier ou invitée
+	Role      string                 `json:"role,omitempty"`
+	Scopes    []string               `json:"scopes,omitempty"`
+	Type      string                 `json:"type"`
+	SessionID uint                   `json:"sid,omitempty"`
+	OrgID     uint                   `json:"org_id,omitempty"`    // organisation active de la session
+	ClientID  string                 `json:"client_id,omitempty"` // client OAuth pour le compte duquel le token est émis
+	FamilyID  string                 `json:"fam,omitempty"`       // famille du refresh token
+	Extra     map[string]interface{} `json:"ext,omitempty"`       // claims personnalisés (voir RegisterClaimsHook)
+	jwt.RegisteredClaims
+}
+
+// UserID retourne l'ID de l'utilisateur porté par le claim "sub".
+func (c *Claims) UserID() uint {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0
 	}
+	return uint(id)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// ServiceClientID retourne le client_id d'un token de service (claim "sub" en
+// "client:<client_id>"), vide pour un token d'utilisateur.
+func (c *Claims) ServiceClientID() string {
+	if !strings.HasPrefix(c.Subject, ServiceSubjectPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(c.Subject, ServiceSubjectPrefix)
 }
 
-func GenerateRefreshToken(userID uint, duration time.Duration) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(duration).Unix(),
-		"iss":     "go-auth-api",
-		"type":    "refresh",
+// IssuedAtUnix retourne la date d'émission (iat) en secondes, 0 si absente.
+func (c *Claims) IssuedAtUnix() int64 {
+	if c.IssuedAt == nil {
+		return 0
 	}
+	return c.IssuedAt.Unix()
+}
+
+// ClaimsHook permet d'enrichir les claims d'un token d'accès avant sa signature.
+type ClaimsHook func(user *models.User, claims *Claims)
+
+var claimsHooks []ClaimsHook
+
+// RegisterClaimsHook ajoute un hook appelé à chaque émission de token d'accès.
+func RegisterClaimsHook(hook ClaimsHook) {
+	claimsHooks = append(claimsHooks, hook)
+}
+
+// NewTokenID génère un identifiant aléatoire utilisé comme claim "jti".
+func NewTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// tokenIssuer et tokenAudience sont configurables via JWT_ISSUER et JWT_AUDIENCE.
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "go-auth-api"
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+func tokenAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "go-auth-api"
+}
+
+// newClaims prépare les claims standard d'un token.
+func newClaims(subject, tokenType, jti string, expiresAt time.Time) *Claims {
+	now := time.Now()
+	return &Claims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   subject,
+			Issuer:    tokenIssuer(),
+			Audience:  jwt.ClaimStrings{tokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+}
+
+// IssueAccessToken émet un token d'accès pour un utilisateur. S'il est lié à une session,
+// le token reprend son organisation active et, pour un client OAuth, le client et ses scopes.
+func IssueAccessToken(user *models.User, session *models.Session) (string, error) {
+	claims := newClaims(strconv.FormatUint(uint64(user.ID), 10), TokenTypeAccess, NewTokenID(), time.Now().Add(AccessTokenTTL))
+	claims.Username = user.Username
+	claims.Role = user.Role
+	if session != nil {
+		claims.SessionID = session.ID
+		if session.OrganizationID != nil {
+			claims.OrgID = *session.OrganizationID
+		}
+		claims.ClientID = session.ClientID
+		claims.Scopes = strings.Fields(session.Scope)
+	}
+
+	for _, hook := range claimsHooks {
+		hook(user, claims)
+	}
+
+	return SignToken(claims)
+}
+
+// GenerateRefreshToken signe un refresh token appartenant à une famille.
+func GenerateRefreshToken(userID uint, familyID, jti string, expiresAt time.Time) (string, error) {
+	claims := newClaims(strconv.FormatUint(uint64(userID), 10), TokenTypeRefresh, jti, expiresAt)
+	claims.FamilyID = familyID
+
+	return SignToken(claims)
+}
+
+// ParseClaims vérifie un token (signature, expiration, émetteur, audience) et son type.
+// Seul un token d'accès peut désigner un client de service plutôt qu'un utilisateur.
+func ParseClaims(tokenString, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := ParseToken(tokenString, claims)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	if claims.UserID() == 0 && (tokenType != TokenTypeAccess || claims.ServiceClientID() == "") {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
 }
@@ -0,0 +1,264 @@
+// utils/keyring.go
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// KeyRetention est la durée pendant laquelle une clé retirée vérifie encore des tokens :
+// celle du token le plus long qu'elle a pu signer.
+const KeyRetention = RefreshTokenTTL
+
+// keyring contient la clé de signature active et les clés acceptées en vérification.
+type keyring struct {
+	mu         sync.RWMutex
+	active     *SigningKey
+	keys       map[string]*SigningKey
+	loadedAt   time.Time // début de la lecture des clés chargées
+	configured *SigningKey
+
+	reloadMu sync.Mutex // un seul rechargement à la fois pour les kid inconnus
+}
+
+var ring = &keyring{keys: map[string]*SigningKey{}}
+
+// InitKeyring charge le trousseau depuis la base. La clé définie dans la configuration
+// y est importée si elle est nouvelle et devient alors la clé active : changer
+// JWT_SECRET ou JWT_PRIVATE_KEY_FILE équivaut à une rotation avec recouvrement.
+func InitKeyring() error {
+	configured, err := ConfiguredSigningKey()
+	if err != nil {
+		return err
+	}
+	ring.configured = configured
+
+	var count int64
+	if err := config.DB.Model(&models.SigningKey{}).Where("kid = ?", configured.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := storeActiveKey(configured); err != nil {
+			return err
+		}
+		log.Println("Clé de signature importée depuis la configuration, kid:", configured.ID)
+	}
+
+	return ReloadKeyring()
+}
+
+// ReloadKeyring relit les clés encore valides depuis la base.
+func ReloadKeyring() error {
+	started := time.Now()
+	var records []models.SigningKey
+	if err := config.DB.
+		Where("expires_at IS NULL OR expires_at > ?", started).
+		Order("created_at asc").
+		Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := map[string]*SigningKey{}
+	var active *SigningKey
+	for _, record := range records {
+		key, err := UnmarshalSigningKey(record.Algorithm, record.KID, record.PrivateKey)
+		if err != nil {
+			log.Printf("Clé de signature %s ignorée: %v", record.KID, err)
+			continue
+		}
+		keys[key.ID] = key
+		if record.RetiredAt == nil {
+			active = key
+		}
+	}
+	if active == nil {
+		return errors.New("aucune clé de signature active")
+	}
+
+	ring.mu.Lock()
+	ring.active = active
+	ring.keys = keys
+	ring.loadedAt = started
+	ring.mu.Unlock()
+	return nil
+}
+
+// storeActiveKey enregistre une clé comme active et retire les précédentes.
+func storeActiveKey(key *SigningKey) error {
+	private, err := key.MarshalPrivate()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(KeyRetention)
+	tx := config.DB.Begin()
+	if err := tx.Model(&models.SigningKey{}).
+		Where("retired_at IS NULL").
+		Updates(map[string]interface{}{"retired_at": now, "expires_at": expiresAt}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	record := models.SigningKey{
+		KID:        key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: private,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// RotateSigningKey génère une nouvelle clé active. Les clés précédentes sont retirées
+// mais vérifient encore les tokens existants pendant KeyRetention.
+// Un algorithme vide reprend celui de la clé active.
+func RotateSigningKey(algorithm string) (*SigningKey, error) {
+	if algorithm == "" {
+		ring.mu.RLock()
+		if ring.active != nil {
+			algorithm = ring.active.Algorithm
+		}
+		ring.mu.RUnlock()
+	}
+
+	key, err := GenerateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeActiveKey(key); err != nil {
+		return nil, err
+	}
+	if err := ReloadKeyring(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// StartKeyringRefresh recharge périodiquement le trousseau (rotation faite par une autre instance)
+// et efface le matériel des clés dont la période de vérification est terminée.
+// Les lignes sont conservées pour qu'une clé de configuration expirée ne soit pas réimportée.
+func StartKeyringRefresh(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := ReloadKeyring(); err != nil {
+				log.Println("Erreur lors du rechargement des clés de signature:", err)
+			}
+			config.DB.Model(&models.SigningKey{}).
+				Where("expires_at < ? AND private_key <> ''", time.Now()).
+				Update("private_key", "")
+		}
+	}()
+}
+
+// verificationKey retourne la clé correspondant au kid. Un kid inconnu (clé créée par une
+// autre instance) déclenche un rechargement du trousseau avant le refus du token.
+func verificationKey(kid string) (*SigningKey, error) {
+	requestedAt := time.Now()
+	if key, ok := lookupVerificationKey(kid); ok {
+		return key, nil
+	}
+
+	// Les requêtes simultanées partagent le même rechargement : inutile de relire la base
+	// si elle l'a été depuis l'arrivée de celle-ci
+	ring.reloadMu.Lock()
+	ring.mu.RLock()
+	fresh := ring.loadedAt.After(requestedAt)
+	ring.mu.RUnlock()
+	if !fresh {
+		if err := ReloadKeyring(); err != nil {
+			log.Println("Erreur lors du rechargement des clés de signature:", err)
+		}
+	}
+	ring.reloadMu.Unlock()
+
+	if key, ok := lookupVerificationKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+// lookupVerificationKey cherche la clé dans le trousseau chargé, qui ne contient que les clés
+// non expirées. Un token sans kid, émis avant son introduction, relève de la clé issue de la
+// configuration, soumise à la même expiration.
+func lookupVerificationKey(kid string) (*SigningKey, bool) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	if kid == "" {
+		if ring.configured == nil {
+			return nil, false
+		}
+		kid = ring.configured.ID
+	}
+	key, ok := ring.keys[kid]
+	return key, ok
+}
+
+// SignToken signe des claims avec la clé active et renseigne l'en-tête "kid".
+func SignToken(claims jwt.Claims) (string, error) {
+	ring.mu.RLock()
+	key := ring.active
+	ring.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("aucune clé de signature chargée")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// ParseToken vérifie la signature et la validité d'un token, en choisissant la clé d'après son kid.
+func ParseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, verificationKeyFunc, jwt.WithIssuer(tokenIssuer()), jwt.WithAudience(tokenAudience()))
+}
+
+// verificationKeyFunc choisit la clé de vérification d'un token d'après son kid.
+func verificationKeyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, err := verificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	// L'algorithme du token doit être celui de la clé (pas de confusion HS/RS)
+	if t.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// activeSigningKey retourne la clé de signature active (nil si le trousseau n'est pas chargé).
+func activeSigningKey() *SigningKey {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	return ring.active
+}
+
+// PublicJWKS retourne le jeu de clés publiques (JWKS) permettant de vérifier les tokens :
+// la clé active et les clés retirées encore valides.
+func PublicJWKS() []JWK {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	keys := []JWK{}
+	for _, key := range ring.keys {
+		if key.IsSymmetric() {
+			continue
+		}
+		if jwk, ok := key.JWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
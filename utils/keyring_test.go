@@ -0,0 +1,57 @@
+// utils/keyring_test.go
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+func TestVerificationKey(t *testing.T) {
+	openTestDB(t)
+	if err := config.DB.AutoMigrate(&models.SigningKey{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("JWT_SECRET", "keyring-test-secret")
+	t.Setenv("JWT_KEY_ID", "")
+	if err := InitKeyring(); err != nil {
+		t.Fatal(err)
+	}
+	configuredKID := ring.configured.ID
+
+	// Token sans kid : clé issue de la configuration
+	if key, err := verificationKey(""); err != nil || key.ID != configuredKID {
+		t.Fatalf("token sans kid refusé : %v", err)
+	}
+
+	// Rotation faite par une autre instance : le kid inconnu recharge le trousseau
+	rotated, err := GenerateSigningKey("HS256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storeActiveKey(rotated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verificationKey(rotated.ID); err != nil {
+		t.Fatalf("clé d'une autre instance refusée : %v", err)
+	}
+	if _, err := verificationKey("inconnu"); err == nil {
+		t.Error("kid inconnu accepté")
+	}
+
+	// La clé de configuration retirée vérifie encore pendant KeyRetention, puis plus du tout
+	if _, err := verificationKey(""); err != nil {
+		t.Fatalf("token sans kid refusé pendant la rétention : %v", err)
+	}
+	config.DB.Model(&models.SigningKey{}).Where("kid = ?", configuredKID).Update("expires_at", time.Now().Add(-time.Second))
+	if err := ReloadKeyring(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verificationKey(""); err == nil {
+		t.Error("token sans kid accepté avec une clé de configuration expirée")
+	}
+}
@@ -0,0 +1,252 @@
+// utils/keys.go
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey est une clé de signature des JWT, identifiée par son kid.
+type SigningKey struct {
+	ID        string            // kid placé dans l'en-tête des tokens
+	Algorithm string            // HS256, RS256, ES256 ou EdDSA
+	Method    jwt.SigningMethod // méthode jwt correspondant à Algorithm
+	Private   interface{}       // secret HMAC ou clé privée
+	Public    interface{}       // secret HMAC ou clé publique
+}
+
+// JWK est la représentation publique d'une clé (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// ConfiguredSigningKey charge la clé de signature définie dans la configuration :
+//   - JWT_ALGORITHM : HS256 (défaut), RS256, ES256 ou EdDSA
+//   - JWT_PRIVATE_KEY_FILE : fichier PEM de la clé privée (algorithmes asymétriques)
+//   - JWT_KEY_ID : kid optionnel, par défaut une empreinte de la clé
+func ConfiguredSigningKey() (*SigningKey, error) {
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = "HS256"
+	}
+
+	if algorithm == "HS256" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET est requis pour l'algorithme HS256")
+		}
+		return newHMACSigningKey([]byte(secret), os.Getenv("JWT_KEY_ID")), nil
+	}
+
+	path := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if path == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE est requis pour l'algorithme %s", algorithm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lecture de la clé privée: %w", err)
+	}
+	return ParseSigningKeyPEM(algorithm, data, os.Getenv("JWT_KEY_ID"))
+}
+
+// newHMACSigningKey construit une clé HS256. Sans kid explicite, le kid est dérivé
+// du secret : changer de secret produit donc une nouvelle clé dans le trousseau.
+func newHMACSigningKey(secret []byte, kid string) *SigningKey {
+	if kid == "" {
+		sum := sha256.Sum256(secret)
+		kid = base64.RawURLEncoding.EncodeToString(sum[:])[:16]
+	}
+	return &SigningKey{
+		ID:        kid,
+		Algorithm: "HS256",
+		Method:    jwt.SigningMethodHS256,
+		Private:   secret,
+		Public:    secret,
+	}
+}
+
+// GenerateSigningKey crée une nouvelle clé aléatoire pour l'algorithme demandé.
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var private interface{}
+	var err error
+	switch algorithm {
+	case "HS256":
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return newHMACSigningKey(secret, ""), nil
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("algorithme non supporté: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSigningKeyPEM(algorithm, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "")
+}
+
+// MarshalPrivate sérialise le secret HMAC (base64) ou la clé privée (PEM PKCS#8) pour le stockage.
+func (k *SigningKey) MarshalPrivate() (string, error) {
+	if secret, ok := k.Private.([]byte); ok {
+		return base64.StdEncoding.EncodeToString(secret), nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// UnmarshalSigningKey reconstruit une clé à partir de sa forme stockée (voir MarshalPrivate).
+func UnmarshalSigningKey(algorithm, kid, data string) (*SigningKey, error) {
+	if algorithm == "HS256" {
+		secret, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, err
+		}
+		return newHMACSigningKey(secret, kid), nil
+	}
+	return ParseSigningKeyPEM(algorithm, []byte(data), kid)
+}
+
+// ParseSigningKeyPEM construit une clé asymétrique à partir d'une clé privée PEM
+// (PKCS#8, PKCS#1 ou SEC 1) et vérifie qu'elle correspond à l'algorithme demandé.
+func ParseSigningKeyPEM(algorithm string, data []byte, kid string) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("clé privée PEM invalide")
+	}
+
+	var private interface{}
+	var err error
+	if private, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if private, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, errors.New("format de clé privée non supporté")
+			}
+		}
+	}
+
+	key := &SigningKey{ID: kid, Algorithm: algorithm, Private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != "RS256" {
+			return nil, fmt.Errorf("une clé RSA ne peut pas être utilisée avec %s", algorithm)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		if algorithm != "ES256" || k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("une clé EC %s ne peut pas être utilisée avec %s", k.Curve.Params().Name, algorithm)
+		}
+		key.Method = jwt.SigningMethodES256
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		if algorithm != "EdDSA" {
+			return nil, fmt.Errorf("une clé Ed25519 ne peut pas être utilisée avec %s", algorithm)
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = k.Public()
+	default:
+		return nil, errors.New("type de clé privée non supporté")
+	}
+
+	if key.ID == "" {
+		jwk, _ := key.JWK()
+		key.ID = jwk.Thumbprint()
+	}
+	return key, nil
+}
+
+// IsSymmetric indique si la clé est un secret partagé (non publiable dans le JWKS).
+func (k *SigningKey) IsSymmetric() bool {
+	_, ok := k.Public.([]byte)
+	return ok
+}
+
+// JWK retourne la représentation publique de la clé.
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// Thumbprint calcule l'empreinte RFC 7638 de la clé, utilisée comme kid par défaut.
+func (j JWK) Thumbprint() string {
+	var members interface{}
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{j.Crv, j.Kty, j.X, j.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+// utils/lockout.go
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Seuils de verrouillage : par compte, puis par adresse IP (tous comptes confondus).
+// L'adresse IP est c.ClientIP() : elle ne fait foi que si TRUSTED_PROXIES ne liste que les
+// proxies réellement placés devant l'API, sans quoi un client la choisit via X-Forwarded-For
+// et échappe au compteur par IP.
+const (
+	MaxFailedLoginAttempts = 5
+	AccountLockoutDuration = 15 * time.Minute
+	MaxFailedIPAttempts    = 20
+	IPLockoutDuration      = 15 * time.Minute
+)
+
+// failedLoginWindow : un compteur sans nouvel échec depuis cette durée repart de zéro.
+const failedLoginWindow = time.Hour
+
+// maxLoginDelay plafonne le délai progressif imposé entre deux tentatives.
+const maxLoginDelay = 30 * time.Second
+
+// loginDelay retourne l'attente imposée après un nombre d'échecs consécutifs :
+// aucune pour les deux premiers, puis 1s, 2s, 4s... plafonnée à maxLoginDelay.
+func loginDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+	shift := failures - 3
+	if shift > 5 {
+		return maxLoginDelay
+	}
+	if delay := time.Second << shift; delay < maxLoginDelay {
+		return delay
+	}
+	return maxLoginDelay
+}
+
+// lockFor calcule le verrouillage à appliquer après un échec, et s'il s'agit d'un verrouillage complet.
+func lockFor(failures, max int, lockout time.Duration) (time.Duration, bool) {
+	if failures >= max {
+		return lockout, true
+	}
+	return loginDelay(failures), false
+}
+
+// emailThrottleHash est la clé du compteur d'un email sans compte : l'empreinte de l'adresse
+// normalisée, pour ne pas conserver les adresses saisies.
+func emailThrottleHash(email string) string {
+	return HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// LoginRetryAfter retourne le temps d'attente avant qu'une tentative de connexion soit
+// acceptée pour ce compte, ou pour cet email s'il ne correspond à aucun compte, et pour
+// cette adresse IP. Zéro si la tentative est permise. Un email inconnu est freiné comme un
+// compte existant : la réponse ne révèle pas si l'adresse est inscrite.
+func LoginRetryAfter(user *models.User, email, ip string) time.Duration {
+	now := time.Now()
+	var wait time.Duration
+
+	if user != nil {
+		if user.LockedUntil != nil && user.LockedUntil.After(now) {
+			wait = user.LockedUntil.Sub(now)
+		}
+	} else {
+		var throttle models.LoginEmailThrottle
+		if err := config.DB.Where("email_hash = ?", emailThrottleHash(email)).First(&throttle).Error; err == nil &&
+			throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			wait = throttle.LockedUntil.Sub(now)
+		}
+	}
+
+	var throttle models.LoginThrottle
+	if err := config.DB.Where("ip = ?", ip).First(&throttle).Error; err == nil &&
+		throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		if d := throttle.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// RecordFailedLogin comptabilise un échec de connexion pour l'adresse IP et pour le compte,
+// ou pour l'email s'il ne correspond à aucun compte. Chaque verrouillage d'un compte ou
+// d'une adresse IP est tracé dans ActivityLog.
+func RecordFailedLogin(user *models.User, email, ip string) error {
+	now := time.Now()
+	cutoff := now.Add(-failedLoginWindow)
+
+	if user == nil {
+		if err := recordFailedEmail(email, now); err != nil {
+			return err
+		}
+	} else {
+		if err := config.DB.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_login_attempts + 1 END", cutoff),
+			"last_failed_login_at":  now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := config.DB.Select("id", "failed_login_attempts").First(user, user.ID).Error; err != nil {
+			return err
+		}
+
+		if d, locked := lockFor(user.FailedLoginAttempts, MaxFailedLoginAttempts, AccountLockoutDuration); d > 0 {
+			lockedUntil := now.Add(d)
+			if err := config.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("locked_until", lockedUntil).Error; err != nil {
+				return err
+			}
+			user.LockedUntil = &lockedUntil
+			if locked {
+				LogActivity(user.ID, "account_locked", fmt.Sprintf("Compte verrouillé %s après %d échecs de connexion (IP %s)", d, user.FailedLoginAttempts, ip))
+			}
+		}
+	}
+
+	throttle := models.LoginThrottle{IP: ip, FailedAttempts: 1, LastFailedAt: now}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ip"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failed_attempts"}, Value: gorm.Expr("CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failed_attempts + 1 END", cutoff)},
+			{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+		},
+	}).Create(&throttle).Error; err != nil {
+		return err
+	}
+	if err := config.DB.Where("ip = ?", ip).First(&throttle).Error; err != nil {
+		return err
+	}
+
+	if d, locked := lockFor(throttle.FailedAttempts, MaxFailedIPAttempts, IPLockoutDuration); d > 0 {
+		lockedUntil := now.Add(d)
+		if err := config.DB.Model(&throttle).Update("locked_until", lockedUntil).Error; err != nil {
+			return err
+		}
+		if locked {
+			var userID uint
+			if user != nil {
+				userID = user.ID
+			}
+			LogActivity(userID, "ip_locked", fmt.Sprintf("Adresse IP %s bloquée %s après %d échecs de connexion", ip, d, throttle.FailedAttempts))
+		}
+	}
+	return nil
+}
+
+// recordFailedEmail comptabilise un échec sur un email sans compte, avec les seuils d'un compte.
+func recordFailedEmail(email string, now time.Time) error {
+	hash := emailThrottleHash(email)
+	throttle := models.LoginEmailThrottle{EmailHash: hash, FailedAttempts: 1, LastFailedAt: now}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "email_hash"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failed_attempts"}, Value: gorm.Expr("CASE WHEN login_email_throttles.last_failed_at < ? THEN 1 ELSE login_email_throttles.failed_attempts + 1 END", now.Add(-failedLoginWindow))},
+			{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+		},
+	}).Create(&throttle).Error; err != nil {
+		return err
+	}
+	if err := config.DB.Where("email_hash = ?", hash).First(&throttle).Error; err != nil {
+		return err
+	}
+
+	if d, _ := lockFor(throttle.FailedAttempts, MaxFailedLoginAttempts, AccountLockoutDuration); d > 0 {
+		return config.DB.Model(&throttle).Update("locked_until", now.Add(d)).Error
+	}
+	return nil
+}
+
+// ResetFailedLogins remet à zéro les compteurs d'un compte après une connexion réussie.
+// Le compteur de l'adresse IP n'est pas remis à zéro : se connecter à son propre compte
+// ne doit pas permettre de continuer à tester ceux des autres.
+func ResetFailedLogins(user *models.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	return config.DB.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// UnlockAccount lève le verrouillage d'un compte (action d'administration).
+func UnlockAccount(userID uint) (bool, error) {
+	result := config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// PurgeLoginThrottles supprime les compteurs par IP et par email inactifs.
+func PurgeLoginThrottles() (int64, error) {
+	cutoff, now := time.Now().Add(-failedLoginWindow), time.Now()
+	result := config.DB.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, now).
+		Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		return result.RowsAffected, result.Error
+	}
+	emails := config.DB.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, now).
+		Delete(&models.LoginEmailThrottle{})
+	return result.RowsAffected + emails.RowsAffected, emails.Error
+}
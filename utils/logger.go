@@ -15,3 +15,14 @@ func LogActivity(userID uint, action, details string) {
 	}
 	config.DB.Create(&log)
 }
+
+// LogActivityBy journalise une action effectuée par actorID sur le compte de userID.
+func LogActivityBy(actorID, userID uint, action, details string) {
+	log := models.ActivityLog{
+		UserID:  userID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+	}
+	config.DB.Create(&log)
+}
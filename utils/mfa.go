@@ -0,0 +1,202 @@
+// utils/mfa.go
+
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MFAPendingTokenTTL est la durée laissée pour saisir le second facteur après le mot de passe.
+const MFAPendingTokenTTL = 5 * time.Minute
+
+// MaxMFAAttempts est le nombre de codes tolérés pour un même token "mfa_pending".
+const MaxMFAAttempts = 5
+
+// ErrTooManyMFAAttempts : le token "mfa_pending" a épuisé ses tentatives.
+var ErrTooManyMFAAttempts = errors.New("trop de codes invalides")
+
+// RecoveryCodeCount est le nombre de codes de secours générés à l'activation.
+const RecoveryCodeCount = 10
+
+// totpPeriod et totpSkew : codes de 30 secondes, un pas de décalage toléré de chaque côté.
+const (
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+// recoveryCodeAlphabet exclut les caractères ambigus (0/O, 1/I/L).
+const recoveryCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// GenerateTOTPKey crée un nouveau secret TOTP pour l'utilisateur.
+func GenerateTOTPKey(user *models.User) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      tokenIssuer(),
+		AccountName: user.Email,
+		Period:      totpPeriod,
+	})
+}
+
+// TOTPQRCode retourne le QR code PNG de la clé sous forme de data URI.
+func TOTPQRCode(key *otp.Key) (string, error) {
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ValidateTOTP vérifie un code TOTP pour l'utilisateur. Un code déjà accepté
+// (même pas de temps ou antérieur) est refusé pour empêcher son rejeu.
+func ValidateTOTP(user *models.User, code string) bool {
+	if user.TOTPSecret == "" || code == "" {
+		return false
+	}
+
+	now := time.Now()
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(user.TOTPSecret, at, opts)
+		if err != nil {
+			return false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		// Enregistrement atomique du pas utilisé
+		step := at.Unix() / totpPeriod
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+	return false
+}
+
+// GenerateRecoveryCodes remplace les codes de secours de l'utilisateur et retourne les nouveaux en clair.
+// Ils ne sont stockés que hachés et ne peuvent plus être affichés ensuite.
+func GenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	records := make([]models.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: HashToken(normalizeRecoveryCode(code))}
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return codes, tx.Commit().Error
+}
+
+// UseRecoveryCode consomme un code de secours encore valide.
+func UseRecoveryCode(userID uint, code string) bool {
+	if code == "" {
+		return false
+	}
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// VerifySecondFactor accepte un code TOTP ou, à défaut, un code de secours.
+func VerifySecondFactor(user *models.User, code string) (bool, string) {
+	if ValidateTOTP(user, code) {
+		return true, "totp"
+	}
+	if UseRecoveryCode(user.ID, code) {
+		return true, "recovery_code"
+	}
+	return false, ""
+}
+
+// IssueMFAPendingToken émet le token intermédiaire à échanger contre un code sur /api/login/mfa.
+func IssueMFAPendingToken(user *models.User) (string, error) {
+	claims := newClaims(strconv.FormatUint(uint64(user.ID), 10), TokenTypeMFAPending, NewTokenID(), time.Now().Add(MFAPendingTokenTTL))
+	return SignToken(claims)
+}
+
+// ReserveMFAAttempt comptabilise un code présenté avec le token "mfa_pending", avant sa
+// vérification : l'incrément est conditionnel, des requêtes simultanées ne peuvent donc pas
+// dépasser MaxMFAAttempts. Retourne ErrTooManyMFAAttempts une fois la limite atteinte.
+func ReserveMFAAttempt(claims *Claims) error {
+	attempt := models.MFAAttempt{JTI: claims.ID, UserID: claims.UserID(), ExpiresAt: claims.ExpiresAt.Time}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&attempt).Error; err != nil {
+		return err
+	}
+	result := config.DB.Model(&models.MFAAttempt{}).
+		Where("jti = ? AND attempts < ?", claims.ID, MaxMFAAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTooManyMFAAttempts
+	}
+	return nil
+}
+
+// PurgeExpiredMFAAttempts supprime les compteurs des tokens "mfa_pending" expirés.
+func PurgeExpiredMFAAttempts() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.MFAAttempt{})
+	return result.RowsAffected, result.Error
+}
+
+// randomRecoveryCode génère un code au format XXXXX-XXXXX.
+func randomRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// normalizeRecoveryCode ignore la casse, les espaces et les tirets saisis.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,346 @@
+// utils/oauth.go
+
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// AuthorizationCodeTTL est la durée de validité d'un code d'autorisation OAuth.
+const AuthorizationCodeTTL = 5 * time.Minute
+
+// OAuthScopes sont les scopes qu'un client OAuth peut recevoir : ceux des routes de l'API et
+// ceux d'OpenID Connect.
+var OAuthScopes = append(append([]string{}, apiScopes...), ScopeOpenID, ScopeProfile, ScopeEmail)
+
+// Codes d'erreur OAuth 2.0 (RFC 6749, section 5.2 et 4.1.2.1).
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+	OAuthConsentRequired         = "consent_required"
+)
+
+// OAuthError est une erreur renvoyée telle quelle au client OAuth.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + " : " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// pkceChallengePattern : empreinte SHA-256 encodée en base64url sans padding (43 caractères).
+var pkceChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// pkceVerifierPattern : code_verifier de 43 à 128 caractères non réservés (RFC 7636, section 4.1).
+var pkceVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+// ValidOAuthScope indique si scope fait partie de OAuthScopes.
+func ValidOAuthScope(scope string) bool {
+	for _, s := range OAuthScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// randomHex retourne n octets aléatoires encodés en hexadécimal.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateOAuthClient enregistre un client OAuth. Le secret d'un client confidentiel n'est
+// retourné qu'ici ; un client public n'en a pas.
+func CreateOAuthClient(name string, redirectURIs, logoutURIs, scopes []string, confidential bool, ownerID uint) (*models.OAuthClient, string, error) {
+	id, err := randomHex(12)
+	if err != nil {
+		return nil, "", err
+	}
+	client := models.OAuthClient{
+		ClientID:     "cl_" + id,
+		Name:         name,
+		Confidential: confidential,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		LogoutURIs:   strings.Join(logoutURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		OwnerID:      ownerID,
+	}
+
+	var secret string
+	if confidential {
+		if secret, err = randomHex(32); err != nil {
+			return nil, "", err
+		}
+		secret = "cs_" + secret
+		client.SecretHash = HashToken(secret)
+	}
+
+	if err := config.DB.Create(&client).Error; err != nil {
+		return nil, "", err
+	}
+	return &client, secret, nil
+}
+
+// RotateOAuthClientSecret remplace le secret d'un client confidentiel et retourne le nouveau.
+func RotateOAuthClientSecret(client *models.OAuthClient) (string, error) {
+	if !client.Confidential {
+		return "", oauthError(OAuthInvalidRequest, "un client public n'a pas de secret")
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	secret = "cs_" + secret
+	if err := config.DB.Model(client).Update("secret_hash", HashToken(secret)).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RevokeOAuthClient désactive un client et ferme toutes les sessions ouvertes à son nom.
+func RevokeOAuthClient(client *models.OAuthClient) error {
+	if err := config.DB.Model(client).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return RevokeOAuthClientSessions(client.ClientID, 0)
+}
+
+// RevokeOAuthClientSessions ferme les sessions ouvertes par un client OAuth, pour un
+// utilisateur donné ou pour tous (userID = 0).
+func RevokeOAuthClientSessions(clientID string, userID uint) error {
+	query := config.DB.Where("client_id = ? AND revoked_at IS NULL", clientID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var sessions []models.Session
+	if err := query.Find(&sessions).Error; err != nil {
+		return err
+	}
+	for i := range sessions {
+		if err := RevokeSession(&sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindOAuthClient charge un client actif par son client_id.
+func FindOAuthClient(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if clientID == "" || config.DB.Where("client_id = ? AND revoked_at IS NULL", clientID).First(&client).Error != nil {
+		return nil, oauthError(OAuthInvalidClient, "client inconnu ou désactivé")
+	}
+	return &client, nil
+}
+
+// AuthenticateOAuthClient authentifie un client à /oauth/token. Un client confidentiel doit
+// présenter son secret ; un client public n'en présente pas.
+func AuthenticateOAuthClient(clientID, secret string) (*models.OAuthClient, error) {
+	client, err := FindOAuthClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.Confidential {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(client.SecretHash)) != 1 {
+			return nil, oauthError(OAuthInvalidClient, "authentification du client échouée")
+		}
+	} else if secret != "" {
+		return nil, oauthError(OAuthInvalidClient, "un client public n'a pas de secret")
+	}
+	return client, nil
+}
+
+// OAuthRedirectAllowed indique si redirectURI fait partie des URI enregistrées du client
+// (comparaison exacte).
+func OAuthRedirectAllowed(client *models.OAuthClient, redirectURI string) bool {
+	return containsString(strings.Fields(client.RedirectURIs), redirectURI)
+}
+
+// OAuthLogoutRedirectAllowed indique si uri fait partie des post_logout_redirect_uris du client.
+func OAuthLogoutRedirectAllowed(client *models.OAuthClient, uri string) bool {
+	return containsString(strings.Fields(client.LogoutURIs), uri)
+}
+
+// ResolveOAuthScope vérifie les scopes demandés par un client et retourne la liste normalisée.
+// Sans scope demandé, le client reçoit tous ceux qui lui sont permis.
+func ResolveOAuthScope(client *models.OAuthClient, requested string) (string, error) {
+	return resolveScope(strings.Fields(client.Scopes), requested)
+}
+
+// resolveScope vérifie des scopes demandés par rapport aux scopes permis (tous si aucun n'est
+// demandé) et retourne la liste sans doublons.
+func resolveScope(allowed []string, requested string) (string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	if len(scopes) == 0 {
+		return "", oauthError(OAuthInvalidScope, "aucun scope demandé")
+	}
+
+	seen := map[string]bool{}
+	var resolved []string
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		if !containsString(allowed, scope) {
+			return "", oauthError(OAuthInvalidScope, "scope non autorisé pour ce client : "+scope)
+		}
+		seen[scope] = true
+		resolved = append(resolved, scope)
+	}
+	return strings.Join(resolved, " "), nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, s := range list {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPKCEChallenge indique si un code_challenge a la forme d'une empreinte S256.
+func ValidPKCEChallenge(challenge string) bool {
+	return pkceChallengePattern.MatchString(challenge)
+}
+
+// VerifyPKCE vérifie qu'un code_verifier correspond au code_challenge (méthode S256).
+func VerifyPKCE(challenge, verifier string) bool {
+	if !pkceVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// CreateAuthorizationCode émet un code d'autorisation pour un utilisateur ayant donné son
+// accord. record décrit la demande (client, utilisateur, redirect_uri, scope, PKCE, nonce) ;
+// son empreinte et son expiration sont renseignées ici.
+func CreateAuthorizationCode(record *models.OAuthAuthorizationCode) (string, error) {
+	code, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	record.CodeHash = HashToken(code)
+	record.ExpiresAt = time.Now().Add(AuthorizationCodeTTL)
+	if err := config.DB.Create(record).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// RedeemAuthorizationCode consomme un code d'autorisation au profit du client qui l'a obtenu.
+// Un code rejoué ferme la session ouverte lors du premier échange (RFC 6749, section 4.1.2).
+func RedeemAuthorizationCode(client *models.OAuthClient, code, redirectURI, codeVerifier string) (*models.OAuthAuthorizationCode, error) {
+	var record models.OAuthAuthorizationCode
+	if err := config.DB.Where("code_hash = ?", HashToken(code)).First(&record).Error; err != nil {
+		return nil, oauthError(OAuthInvalidGrant, "code d'autorisation invalide")
+	}
+	if record.ClientID != client.ClientID {
+		return nil, oauthError(OAuthInvalidGrant, "code d'autorisation invalide")
+	}
+	if record.UsedAt != nil {
+		if record.SessionID != 0 {
+			var session models.Session
+			if config.DB.First(&session, record.SessionID).Error == nil {
+				RevokeSession(&session)
+			}
+		}
+		return nil, oauthError(OAuthInvalidGrant, "code d'autorisation déjà utilisé")
+	}
+	if record.ExpiresAt.Before(time.Now()) {
+		return nil, oauthError(OAuthInvalidGrant, "code d'autorisation expiré")
+	}
+	if record.RedirectURI != redirectURI {
+		return nil, oauthError(OAuthInvalidGrant, "redirect_uri différente de celle de l'autorisation")
+	}
+	if !VerifyPKCE(record.CodeChallenge, codeVerifier) {
+		return nil, oauthError(OAuthInvalidGrant, "code_verifier invalide")
+	}
+
+	// Seul le premier échange marque le code comme utilisé
+	now := time.Now()
+	result := config.DB.Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, oauthError(OAuthInvalidGrant, "code d'autorisation déjà utilisé")
+	}
+	record.UsedAt = &now
+	return &record, nil
+}
+
+// AttachAuthorizationCodeSession retient la session ouverte avec un code, pour pouvoir la
+// fermer si le code est rejoué.
+func AttachAuthorizationCodeSession(record *models.OAuthAuthorizationCode, sessionID uint) error {
+	return config.DB.Model(record).Update("session_id", sessionID).Error
+}
+
+// HasOAuthConsent indique si l'utilisateur a déjà accordé tous ces scopes au client.
+func HasOAuthConsent(userID uint, clientID, scope string) bool {
+	var consent models.OAuthConsent
+	if err := config.DB.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		return false
+	}
+	granted := strings.Fields(consent.Scope)
+	for _, s := range strings.Fields(scope) {
+		if !containsString(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveOAuthConsent ajoute des scopes à l'accord donné par l'utilisateur au client.
+func SaveOAuthConsent(userID uint, clientID, scope string) error {
+	consent := models.OAuthConsent{UserID: userID, ClientID: clientID}
+	if err := config.DB.Where(consent).FirstOrCreate(&consent).Error; err != nil {
+		return err
+	}
+	granted := strings.Fields(consent.Scope)
+	for _, s := range strings.Fields(scope) {
+		if !containsString(granted, s) {
+			granted = append(granted, s)
+		}
+	}
+	return config.DB.Model(&consent).Update("scope", strings.Join(granted, " ")).Error
+}
+
+// PurgeExpiredAuthorizationCodes supprime les codes d'autorisation expirés.
+func PurgeExpiredAuthorizationCodes() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthAuthorizationCode{})
+	return result.RowsAffected, result.Error
+}
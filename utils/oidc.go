@@ -0,0 +1,192 @@
+// utils/oidc.go
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// Scopes OpenID Connect : "openid" demande un id_token, "profile" et "email" les claims
+// correspondants dans l'id_token et à /oauth/userinfo.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Méthodes d'authentification (claim "amr", RFC 8176).
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRMultiFactor  = "mfa"
+	AMRHardwareKey  = "hwk"
+	AMRUserPresence = "user"
+)
+
+// Niveaux d'authentification (claim "acr") : 1 pour un seul facteur, 2 pour une
+// double authentification ou un passkey.
+const (
+	ACRSingleFactor = "1"
+	ACRMultiFactor  = "2"
+)
+
+// ErrOIDCUnavailable : les id_tokens doivent être vérifiables par les clients avec le JWKS,
+// ce qui exclut une clé de signature symétrique (HS256).
+var ErrOIDCUnavailable = errors.New("OpenID Connect requiert OIDC_ISSUER, OIDC_AUTHORIZATION_URL et une clé de signature asymétrique (JWT_ALGORITHM)")
+
+// OIDCUserClaims sont les claims décrivant l'utilisateur, selon les scopes accordés.
+type OIDCUserClaims struct {
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	UpdatedAt         int64  `json:"updated_at,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// IDTokenClaims sont les claims d'un id_token (OpenID Connect Core, section 2).
+type IDTokenClaims struct {
+	Nonce    string   `json:"nonce,omitempty"`
+	AuthTime int64    `json:"auth_time,omitempty"`
+	ACR      string   `json:"acr,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	AZP      string   `json:"azp,omitempty"`
+	SID      string   `json:"sid,omitempty"` // session du client, utilisée par la déconnexion
+	OIDCUserClaims
+	jwt.RegisteredClaims
+}
+
+// OIDCAvailable indique si OIDC_ISSUER et OIDC_AUTHORIZATION_URL sont configurés et si la
+// clé de signature active permet d'émettre des id_tokens.
+func OIDCAvailable() bool {
+	if OIDCIssuer() == "" || OIDCAuthorizationEndpoint() == "" {
+		return false
+	}
+	key := activeSigningKey()
+	return key != nil && !key.IsSymmetric()
+}
+
+// OIDCSigningAlgorithms retourne les algorithmes des clés publiées dans le JWKS.
+func OIDCSigningAlgorithms() []string {
+	algorithms := []string{}
+	for _, jwk := range PublicJWKS() {
+		if !containsString(algorithms, jwk.Alg) {
+			algorithms = append(algorithms, jwk.Alg)
+		}
+	}
+	return algorithms
+}
+
+// OIDCIssuer retourne l'identifiant de l'émetteur OpenID Connect (OIDC_ISSUER), vide s'il
+// n'est pas configuré. Il n'est jamais déduit de la requête : Host et X-Forwarded-Proto sont
+// fournis par le client.
+func OIDCIssuer() string {
+	return strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/")
+}
+
+// OIDCAuthorizationEndpoint retourne la page du frontend qui affiche la connexion et le
+// consentement (OIDC_AUTHORIZATION_URL). GET /oauth/authorize exige un token Bearer : un
+// navigateur redirigé par une application ne peut pas l'appeler directement.
+func OIDCAuthorizationEndpoint() string {
+	return os.Getenv("OIDC_AUTHORIZATION_URL")
+}
+
+// HasScope indique si scope figure dans une liste de scopes séparés par des espaces.
+func HasScope(scopes, scope string) bool {
+	return containsString(strings.Fields(scopes), scope)
+}
+
+// SessionACR retourne le niveau d'authentification d'une session d'après ses méthodes.
+func SessionACR(authMethods string) string {
+	methods := strings.Fields(authMethods)
+	if containsString(methods, AMRMultiFactor) || containsString(methods, AMRHardwareKey) {
+		return ACRMultiFactor
+	}
+	return ACRSingleFactor
+}
+
+// UserClaimsForScope retourne les claims de l'utilisateur autorisés par les scopes accordés.
+// baseURL complète le chemin relatif de l'avatar.
+func UserClaimsForScope(user *models.User, scope, baseURL string) OIDCUserClaims {
+	var claims OIDCUserClaims
+	if HasScope(scope, ScopeProfile) {
+		claims.PreferredUsername = user.Username
+		claims.Name = user.Username
+		claims.UpdatedAt = user.UpdatedAt.Unix()
+		if user.Avatar != "" {
+			claims.Picture = baseURL + "/" + strings.TrimPrefix(user.Avatar, "/")
+		}
+	}
+	if HasScope(scope, ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	return claims
+}
+
+// IssueIDToken émet l'id_token d'une session ouverte par un client OAuth. nonce est celui
+// de la demande d'autorisation (vide lors d'un renouvellement).
+func IssueIDToken(user *models.User, session *models.Session, nonce string) (string, error) {
+	if !OIDCAvailable() {
+		return "", ErrOIDCUnavailable
+	}
+	issuer := OIDCIssuer()
+
+	now := time.Now()
+	claims := IDTokenClaims{
+		Nonce:          nonce,
+		AuthTime:       SessionAuthTime(session).Unix(),
+		ACR:            SessionACR(session.AuthMethods),
+		AMR:            strings.Fields(session.AuthMethods),
+		AZP:            session.ClientID,
+		SID:            strconv.FormatUint(uint64(session.ID), 10),
+		OIDCUserClaims: UserClaimsForScope(user, session.Scope, issuer),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewTokenID(),
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{session.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
+	}
+	return SignToken(&claims)
+}
+
+// ParseIDTokenHint vérifie la signature et l'émetteur d'un id_token présenté comme
+// id_token_hint. Un id_token expiré reste accepté (OpenID Connect RP-Initiated Logout).
+func ParseIDTokenHint(tokenString string) (*IDTokenClaims, error) {
+	issuer := OIDCIssuer()
+	if issuer == "" {
+		return nil, ErrOIDCUnavailable
+	}
+	var claims IDTokenClaims
+	if _, err := jwt.ParseWithClaims(tokenString, &claims, verificationKeyFunc, jwt.WithoutClaimsValidation()); err != nil {
+		return nil, err
+	}
+	if claims.Issuer != issuer || claims.AZP == "" || !containsString(claims.Audience, claims.AZP) {
+		return nil, errors.New("id_token_hint invalide")
+	}
+	return &claims, nil
+}
+
+// EndOAuthSession ferme la session d'un client désignée par le claim "sid" d'un id_token.
+// Retourne false si la session n'existe pas ou est déjà fermée.
+func EndOAuthSession(sessionID, userID uint, clientID string) (bool, error) {
+	var session models.Session
+	err := config.DB.Where("id = ? AND user_id = ? AND client_id = ? AND revoked_at IS NULL", sessionID, userID, clientID).
+		First(&session).Error
+	if err != nil {
+		return false, nil
+	}
+	return true, RevokeSession(&session)
+}
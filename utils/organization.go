@@ -0,0 +1,261 @@
+// utils/organization.go
+
+package utils
+
+import (
+	"errors"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+)
+
+// OrgScopedPermissions sont les seules permissions qu'un rôle attribué dans une organisation
+// peut donner : elles ne portent que sur les membres de cette organisation. Les autres
+// (suppression définitive, clés de signature, rôles...) restent réservées aux rôles globaux.
+var OrgScopedPermissions = []string{
+	PermUsersRead,
+	PermUsersUnlock,
+	PermSessionsAdmin,
+	PermLogsRead,
+	PermOrgsManage,
+}
+
+var (
+	ErrNotMember     = errors.New("l'utilisateur n'est pas membre de l'organisation")
+	ErrAlreadyMember = errors.New("l'utilisateur est déjà membre de l'organisation")
+	ErrLastOrgAdmin  = errors.New("l'organisation doit garder au moins un membre pouvant la gérer")
+)
+
+// IsOrgScopedPermission indique si une permission peut être accordée au sein d'une organisation.
+func IsOrgScopedPermission(permission string) bool {
+	for _, p := range OrgScopedPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// MembershipPermissions retourne les permissions accordées à un utilisateur par ses rôles
+// dans une organisation, limitées à OrgScopedPermissions.
+func MembershipPermissions(userID, orgID uint) ([]string, error) {
+	var names []string
+	err := config.DB.Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN membership_roles ON membership_roles.role_id = role_permissions.role_id").
+		Joins("JOIN memberships ON memberships.id = membership_roles.membership_id").
+		Where("memberships.user_id = ? AND memberships.organization_id = ?", userID, orgID).
+		Where("permissions.name IN ?", OrgScopedPermissions).
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// OrgMemberIDs retourne une sous-requête des IDs des membres d'une organisation,
+// à utiliser dans un filtre "user_id IN (?)".
+func OrgMemberIDs(orgID uint) *gorm.DB {
+	return config.DB.Model(&models.Membership{}).Select("user_id").Where("organization_id = ?", orgID)
+}
+
+// FindMembership charge l'appartenance d'un utilisateur à une organisation, avec ses rôles.
+func FindMembership(orgID, userID uint) (*models.Membership, error) {
+	var membership models.Membership
+	err := config.DB.Preload("Roles").
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// IsMember indique si un utilisateur appartient à une organisation.
+func IsMember(orgID, userID uint) bool {
+	var count int64
+	err := config.DB.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// DefaultOrganizationID retourne l'organisation active d'une nouvelle session : la plus
+// ancienne appartenance de l'utilisateur, ou nil s'il n'appartient à aucune organisation.
+func DefaultOrganizationID(userID uint) *uint {
+	var membership models.Membership
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at, id").First(&membership).Error; err != nil {
+		return nil
+	}
+	return &membership.OrganizationID
+}
+
+// findRolesByName charge des rôles par leur nom ; un nom inconnu donne ErrRoleNotFound.
+func findRolesByName(tx *gorm.DB, names []string) ([]models.Role, error) {
+	roles := []models.Role{}
+	if len(names) == 0 {
+		return roles, nil
+	}
+	if err := tx.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	if len(roles) != len(names) {
+		return nil, ErrRoleNotFound
+	}
+	return roles, nil
+}
+
+// countOrgManagers compte les membres d'une organisation, hors excludeUserID, dont les rôles
+// donnent la permission orgs:manage.
+func countOrgManagers(tx *gorm.DB, orgID, excludeUserID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Membership{}).
+		Where("memberships.organization_id = ? AND memberships.user_id <> ?", orgID, excludeUserID).
+		Where(`EXISTS (SELECT 1 FROM membership_roles
+			JOIN role_permissions ON role_permissions.role_id = membership_roles.role_id
+			JOIN permissions ON permissions.id = role_permissions.permission_id
+			WHERE membership_roles.membership_id = memberships.id AND permissions.name = ?)`, PermOrgsManage).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateOrganization crée une organisation dont ownerID devient membre avec le rôle admin.
+func CreateOrganization(name, slug string, ownerID uint) (*models.Organization, error) {
+	org := models.Organization{Name: name, Slug: slug}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		roles, err := findRolesByName(tx, []string{RoleAdmin})
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.Membership{OrganizationID: org.ID, UserID: ownerID, Roles: roles}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// DeleteOrganization supprime une organisation, ses appartenances et ses invitations. Les
+// sessions dont elle était l'organisation active n'en ont plus.
+func DeleteOrganization(org *models.Organization) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM membership_roles WHERE membership_id IN
+			(SELECT id FROM memberships WHERE organization_id = ?)`, org.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).Where("organization_id = ?", org.ID).
+			Update("organization_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(org).Error
+	})
+}
+
+// AddMember rattache un utilisateur à une organisation avec les rôles indiqués
+// (par défaut le rôle user).
+func AddMember(orgID, userID uint, roleNames []string) (*models.Membership, error) {
+	return addMember(config.DB, orgID, userID, roleNames)
+}
+
+// addMember est AddMember au sein d'une transaction.
+func addMember(tx *gorm.DB, orgID, userID uint, roleNames []string) (*models.Membership, error) {
+	if len(roleNames) == 0 {
+		roleNames = []string{RoleUser}
+	}
+	var count int64
+	if err := tx.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyMember
+	}
+	roles, err := findRolesByName(tx, roleNames)
+	if err != nil {
+		return nil, err
+	}
+	membership := models.Membership{OrganizationID: orgID, UserID: userID, Roles: roles}
+	if err := tx.Create(&membership).Error; err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// SetMemberRoles remplace les rôles d'un membre. L'organisation doit garder au moins
+// un membre pouvant la gérer.
+func SetMemberRoles(membership *models.Membership, roleNames []string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		roles, err := findRolesByName(tx, roleNames)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(membership).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		return ensureOrgManaged(tx, membership.OrganizationID, membership.UserID)
+	})
+}
+
+// RemoveMember retire un utilisateur d'une organisation. Ses sessions dont c'était
+// l'organisation active n'en ont plus.
+func RemoveMember(membership *models.Membership) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		managers, err := countOrgManagers(tx, membership.OrganizationID, membership.UserID)
+		if err != nil {
+			return err
+		}
+		if managers == 0 {
+			return ErrLastOrgAdmin
+		}
+		if err := tx.Model(membership).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Delete(membership).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND organization_id = ?", membership.UserID, membership.OrganizationID).
+			Update("organization_id", nil).Error
+	})
+}
+
+// ensureOrgManaged vérifie, après modification des rôles de userID, qu'un membre au moins
+// peut encore gérer l'organisation.
+func ensureOrgManaged(tx *gorm.DB, orgID, userID uint) error {
+	others, err := countOrgManagers(tx, orgID, userID)
+	if err != nil || others > 0 {
+		return err
+	}
+	self, err := countOrgManagers(tx, orgID, 0)
+	if err != nil {
+		return err
+	}
+	if self == 0 {
+		return ErrLastOrgAdmin
+	}
+	return nil
+}
+
+// SetSessionOrganization change l'organisation active d'une session et retourne la session à jour.
+func SetSessionOrganization(sessionID, orgID uint) (*models.Session, error) {
+	var session models.Session
+	if err := config.DB.First(&session, sessionID).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(&session).Update("organization_id", orgID).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
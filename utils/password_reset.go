@@ -0,0 +1,123 @@
+// utils/password_reset.go
+
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/mailer"
+	"github.com/kdev1966/go-auth-api/models"
+	"github.com/kdev1966/go-auth-api/passwords"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTokenTTL est la durée de validité d'un lien de réinitialisation.
+const PasswordResetTokenTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("token de réinitialisation invalide, expiré ou déjà utilisé")
+
+// CreatePasswordResetToken génère un token de réinitialisation et n'en stocke que l'empreinte.
+// Les tokens encore valides d'une demande précédente sont invalidés.
+func CreatePasswordResetToken(userID uint, ip string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: HashToken(token),
+			IP:        ip,
+			ExpiresAt: time.Now().Add(PasswordResetTokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SendPasswordResetEmail envoie le lien de réinitialisation à l'utilisateur.
+// Le lien pointe vers PASSWORD_RESET_URL (page du front qui appelle POST /api/password/reset).
+func SendPasswordResetEmail(user *models.User, token string) error {
+	var body string
+	if url := os.Getenv("PASSWORD_RESET_URL"); url != "" {
+		body = fmt.Sprintf("Bonjour %s,\n\nPour choisir un nouveau mot de passe, ouvrez ce lien :\n%s?token=%s\n\nCe lien expire dans %s. Si vous n'êtes pas à l'origine de cette demande, ignorez cet email.\n",
+			user.Username, url, token, PasswordResetTokenTTL)
+	} else {
+		body = fmt.Sprintf("Bonjour %s,\n\nPour choisir un nouveau mot de passe, utilisez ce token :\n%s\n\nCe token expire dans %s. Si vous n'êtes pas à l'origine de cette demande, ignorez cet email.\n",
+			user.Username, token, PasswordResetTokenTTL)
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Réinitialisation de votre mot de passe",
+		Body:    body,
+	})
+}
+
+// ResetPassword consomme un token de réinitialisation et remplace le mot de passe.
+// Toutes les sessions et tous les tokens de l'utilisateur sont ensuite révoqués.
+func ResetPassword(token, newPassword string) (*models.User, error) {
+	now := time.Now()
+	var reset models.PasswordResetToken
+	if err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", HashToken(token), now).First(&reset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidResetToken
+		}
+		return nil, err
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, reset.UserID).Error; err != nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	// Un mot de passe refusé par la politique ne consomme pas le token
+	if err := passwords.Validate(newPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	// Marquage atomique : deux requêtes concurrentes ne peuvent pas consommer le même token
+	result := config.DB.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", reset.ID, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidResetToken
+	}
+
+	hashedPassword, err := passwords.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		return nil, err
+	}
+
+	if err := RevokeAllUserTokens(user.ID); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PurgeExpiredPasswordResetTokens supprime les tokens de réinitialisation expirés.
+func PurgeExpiredPasswordResetTokens() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,201 @@
+// utils/rbac.go
+
+package utils
+
+import (
+	"errors"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Permissions connues de l'API.
+const (
+	PermUsersRead     = "users:read"     // consulter et lister tous les utilisateurs
+	PermUsersUpdate   = "users:update"   // modifier n'importe quel utilisateur
+	PermUsersDelete   = "users:delete"   // supprimer n'importe quel utilisateur
+	PermUsersPurge    = "users:purge"    // supprimer définitivement un utilisateur
+	PermUsersRestore  = "users:restore"  // restaurer un utilisateur supprimé
+	PermUsersUnlock   = "users:unlock"   // déverrouiller un compte
+	PermSessionsAdmin = "sessions:admin" // voir et fermer les sessions des autres utilisateurs
+	PermLogsRead      = "logs:read"      // consulter le journal d'activité de tous les utilisateurs
+	PermKeysManage    = "keys:manage"    // gérer les clés de signature
+	PermRolesManage   = "roles:manage"   // gérer les rôles et leur attribution
+	PermOrgsManage    = "orgs:manage"    // gérer une organisation et ses membres
+	PermClientsManage = "clients:manage" // gérer les clients OAuth et les clients de service
+	PermTokensInspect = "tokens:inspect" // introspecter les tokens de tous les utilisateurs (passerelle d'API)
+)
+
+// Rôles créés au démarrage.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+	RoleUser    = "user"
+)
+
+// DefaultPermissions décrit les permissions créées au démarrage.
+var DefaultPermissions = []models.Permission{
+	{Name: PermUsersRead, Description: "Consulter et lister tous les utilisateurs"},
+	{Name: PermUsersUpdate, Description: "Modifier n'importe quel utilisateur"},
+	{Name: PermUsersDelete, Description: "Supprimer n'importe quel utilisateur"},
+	{Name: PermUsersPurge, Description: "Supprimer définitivement un utilisateur"},
+	{Name: PermUsersRestore, Description: "Restaurer un utilisateur supprimé"},
+	{Name: PermUsersUnlock, Description: "Déverrouiller un compte"},
+	{Name: PermSessionsAdmin, Description: "Voir et fermer les sessions des autres utilisateurs"},
+	{Name: PermLogsRead, Description: "Consulter le journal d'activité de tous les utilisateurs"},
+	{Name: PermKeysManage, Description: "Gérer les clés de signature des tokens"},
+	{Name: PermRolesManage, Description: "Gérer les rôles et leur attribution"},
+	{Name: PermOrgsManage, Description: "Gérer une organisation et ses membres"},
+	{Name: PermClientsManage, Description: "Gérer les clients OAuth et les clients de service"},
+	{Name: PermTokensInspect, Description: "Introspecter les tokens de tous les utilisateurs"},
+}
+
+// defaultRoles associe chaque rôle créé au démarrage à ses permissions.
+// Le rôle admin reçoit toujours toutes les permissions.
+var defaultRoles = []struct {
+	Name        string
+	Description string
+	Permissions []string
+}{
+	{RoleAdmin, "Administrateur : toutes les permissions", nil},
+	{RoleSupport, "Support : consultation des comptes et déblocage", []string{PermUsersRead, PermUsersUnlock, PermSessionsAdmin, PermLogsRead}},
+	{RoleUser, "Utilisateur : gestion de son propre compte uniquement", []string{}},
+}
+
+var (
+	ErrRoleNotFound = errors.New("rôle introuvable")
+	ErrLastAdmin    = errors.New("impossible de retirer le rôle au dernier administrateur")
+)
+
+// SeedRBAC crée les permissions et les rôles par défaut, puis attribue un rôle aux
+// utilisateurs qui n'en ont pas encore, d'après leur ancien champ Role.
+func SeedRBAC() error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range DefaultPermissions {
+			permission := p
+			if err := tx.Where(models.Permission{Name: permission.Name}).
+				Assign(models.Permission{Description: permission.Description}).
+				FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+		}
+		var all []models.Permission
+		if err := tx.Find(&all).Error; err != nil {
+			return err
+		}
+
+		for _, r := range defaultRoles {
+			role := models.Role{Name: r.Name}
+			created := tx.Where(models.Role{Name: r.Name}).
+				Attrs(models.Role{Description: r.Description, System: true}).
+				FirstOrCreate(&role)
+			if created.Error != nil {
+				return created.Error
+			}
+
+			switch {
+			case r.Name == RoleAdmin:
+				// Les permissions ajoutées par une nouvelle version reviennent toujours à l'admin
+				if err := tx.Model(&role).Association("Permissions").Replace(all); err != nil {
+					return err
+				}
+			case created.RowsAffected > 0 && len(r.Permissions) > 0:
+				var permissions []models.Permission
+				if err := tx.Where("name IN ?", r.Permissions).Find(&permissions).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&role).Association("Permissions").Append(permissions); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Reprise de l'ancien champ Role : chaque utilisateur sans rôle reçoit celui du même nom
+		return tx.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT users.id, roles.id FROM users
+			JOIN roles ON roles.name = CASE WHEN users.role = '' THEN ? ELSE users.role END
+			WHERE NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = users.id)`, RoleUser).Error
+	})
+}
+
+// UserPermissions retourne les noms des permissions accordées à un utilisateur par ses rôles
+// globaux : ceux qui lui sont attribués directement et ceux de ses groupes, y compris les
+// groupes parents (voir UserGroupIDs).
+func UserPermissions(userID uint) ([]string, error) {
+	var names []string
+	err := config.DB.Raw(userGroupsCTE+`
+		SELECT DISTINCT permissions.name FROM permissions
+		JOIN role_permissions ON role_permissions.permission_id = permissions.id
+		WHERE role_permissions.role_id IN (
+			SELECT role_id FROM user_roles WHERE user_id = ?
+			UNION
+			SELECT role_id FROM group_roles WHERE group_id IN (SELECT id FROM member_groups)
+		)`, userID, userID).Scan(&names).Error
+	return names, err
+}
+
+// AssignRole attribue un rôle (par son nom) à un utilisateur.
+func AssignRole(userID uint, roleName string) (*models.Role, error) {
+	var role models.Role
+	if err := config.DB.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Table("user_roles").
+		Create(map[string]interface{}{"user_id": userID, "role_id": role.ID}).Error
+	return &role, err
+}
+
+// SetPrimaryRole remplace le rôle principal d'un utilisateur : l'ancien rôle principal lui est
+// retiré, le nouveau attribué, ses autres rôles sont conservés. Le dernier administrateur ne
+// peut pas être rétrogradé. Retourne le nom de l'ancien rôle principal.
+func SetPrimaryRole(userID uint, roleName string) (string, error) {
+	var previous string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
+			}
+			return err
+		}
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		previous = user.Role
+		if previous == role.Name {
+			return nil
+		}
+
+		if previous == RoleAdmin {
+			var others int64
+			if err := tx.Table("user_roles").
+				Joins("JOIN roles ON roles.id = user_roles.role_id").
+				Where("roles.name = ? AND user_roles.user_id <> ?", RoleAdmin, userID).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others == 0 {
+				return ErrLastAdmin
+			}
+		}
+
+		if err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ?
+			AND role_id IN (SELECT id FROM roles WHERE name = ?)`, userID, previous).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Table("user_roles").
+			Create(map[string]interface{}{"user_id": userID, "role_id": role.ID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("role", role.Name).Error
+	})
+	return previous, err
+}
@@ -0,0 +1,119 @@
+// utils/refresh_token.go
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// RefreshTokenTTL est la durée de vie d'un refresh token.
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token invalide")
+	ErrRefreshTokenReused  = errors.New("refresh token déjà utilisé")
+)
+
+// IssueRefreshToken émet un refresh token et l'enregistre dans sa famille.
+// Un familyID vide démarre une nouvelle famille (nouvelle connexion).
+func IssueRefreshToken(userID uint, familyID string) (string, error) {
+	if familyID == "" {
+		familyID = NewTokenID()
+	}
+	jti := NewTokenID()
+	expiresAt := time.Now().Add(RefreshTokenTTL)
+
+	tokenString, err := GenerateRefreshToken(userID, familyID, jti, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.RefreshToken{
+		JTI:       jti,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// ParseRefreshToken vérifie la signature et le type d'un refresh token et retourne ses claims.
+func ParseRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := ParseClaims(tokenString, TokenTypeRefresh)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// RotateRefreshToken retire le refresh token présenté et en émet un nouveau dans la même famille.
+// Si un token déjà retiré est présenté à nouveau, toute la famille est révoquée.
+func RotateRefreshToken(tokenString string) (uint, string, error) {
+	_, record, err := ValidateRefreshToken(tokenString)
+	if err != nil {
+		return 0, "", err
+	}
+	userID := record.UserID
+
+	// Retrait atomique : une seule requête concurrente peut consommer le token
+	result := config.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return 0, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Token déjà retiré : probable vol, on révoque toute la famille
+		if err := RevokeRefreshTokenFamily(record.FamilyID); err != nil {
+			return 0, "", err
+		}
+		LogActivity(userID, "refresh_token_reuse", fmt.Sprintf("Réutilisation d'un refresh token retiré, famille %s révoquée", record.FamilyID))
+		return 0, "", ErrRefreshTokenReused
+	}
+
+	newToken, err := IssueRefreshToken(userID, record.FamilyID)
+	if err != nil {
+		return 0, "", err
+	}
+	return userID, newToken, nil
+}
+
+// RevokeRefreshTokenFamily révoque tous les refresh tokens d'une famille et ferme la session associée.
+func RevokeRefreshTokenFamily(familyID string) error {
+	now := time.Now()
+	if err := config.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return config.DB.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// RevokeUserRefreshTokens révoque tous les refresh tokens et sessions d'un utilisateur.
+func RevokeUserRefreshTokens(userID uint) error {
+	now := time.Now()
+	if err := config.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// PurgeExpiredRefreshTokens supprime les refresh tokens expirés.
+func PurgeExpiredRefreshTokens() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,105 @@
+// utils/revocation.go
+
+package utils
+
+import (
+	"log"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm/clause"
+)
+
+// RevokeToken ajoute le jti d'un token dans la liste de révocation jusqu'à son expiration.
+func RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	revoked := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	// Révoquer deux fois le même token n'est pas une erreur
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+}
+
+// RevokeAllUserTokens invalide tous les tokens déjà émis pour un utilisateur.
+func RevokeAllUserTokens(userID uint) error {
+	if err := config.DB.Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("tokens_revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return RevokeUserRefreshTokens(userID)
+}
+
+// IsTokenRevoked indique si un token a été révoqué, soit individuellement (jti),
+// soit par un logout global de l'utilisateur postérieur à son émission (iat).
+func IsTokenRevoked(jti string, userID uint, issuedAt int64) bool {
+	var count int64
+	if jti != "" {
+		if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil || count > 0 {
+			return true
+		}
+	}
+
+	var user models.User
+	if err := config.DB.Select("id", "tokens_revoked_at").First(&user, userID).Error; err != nil {
+		return true
+	}
+	// iat est à la seconde près : un token émis dans la seconde de la révocation (reconnexion
+	// immédiate) reste accepté
+	return user.TokensRevokedAt != nil && issuedAt < user.TokensRevokedAt.Unix()
+}
+
+// PurgeExpiredRevocations supprime les entrées dont le token a de toute façon expiré.
+func PurgeExpiredRevocations() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
+
+// StartRevocationCleanup lance la purge périodique de la liste de révocation, des refresh tokens,
+// des sessions et des challenges WebAuthn expirés.
+func StartRevocationCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := PurgeExpiredRevocations(); err != nil {
+				log.Println("Erreur lors de la purge des tokens révoqués:", err)
+			} else if n > 0 {
+				log.Printf("%d token(s) révoqué(s) expiré(s) purgé(s)", n)
+			}
+			if n, err := PurgeExpiredRefreshTokens(); err != nil {
+				log.Println("Erreur lors de la purge des refresh tokens:", err)
+			} else if n > 0 {
+				log.Printf("%d refresh token(s) expiré(s) purgé(s)", n)
+			}
+			if n, err := PurgeExpiredSessions(); err != nil {
+				log.Println("Erreur lors de la purge des sessions:", err)
+			} else if n > 0 {
+				log.Printf("%d session(s) expirée(s) purgée(s)", n)
+			}
+			if _, err := PurgeExpiredMFAAttempts(); err != nil {
+				log.Println("Erreur lors de la purge des compteurs de codes MFA:", err)
+			}
+			if _, err := PurgeExpiredPasskeyChallenges(); err != nil {
+				log.Println("Erreur lors de la purge des challenges WebAuthn:", err)
+			}
+			if _, err := PurgeExpiredPasswordResetTokens(); err != nil {
+				log.Println("Erreur lors de la purge des tokens de réinitialisation:", err)
+			}
+			if _, err := PurgeExpiredAuthorizationCodes(); err != nil {
+				log.Println("Erreur lors de la purge des codes d'autorisation OAuth:", err)
+			}
+			if _, err := PurgeExpiredDeviceCodes(); err != nil {
+				log.Println("Erreur lors de la purge des demandes d'autorisation d'appareil:", err)
+			}
+			if _, err := PurgeLoginThrottles(); err != nil {
+				log.Println("Erreur lors de la purge des compteurs d'échecs de connexion:", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,128 @@
+// utils/service_client.go
+
+package utils
+
+import (
+	"crypto/subtle"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// ServiceSubjectPrefix précède l'identifiant du client dans le claim "sub" d'un token de
+// service : "client:svc_...". Un token d'utilisateur porte un ID numérique.
+const ServiceSubjectPrefix = "client:"
+
+// ServiceClientIDPrefix distingue le client_id d'un client de service de celui d'un client OAuth.
+const ServiceClientIDPrefix = "svc_"
+
+// ServiceTokenTTL est la durée de vie d'un token de service ; il n'a pas de refresh token,
+// le service en redemande un avec ses identifiants.
+const ServiceTokenTTL = AccessTokenTTL
+
+// serviceScopePattern : les scopes des services sont libres (ex. "orders:read"), sans espace.
+var serviceScopePattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,64}$`)
+
+// ValidServiceScope indique si scope peut être attribué à un client de service.
+func ValidServiceScope(scope string) bool {
+	return serviceScopePattern.MatchString(scope)
+}
+
+// CreateServiceClient enregistre un client de service. Le secret n'est retourné qu'ici.
+func CreateServiceClient(name string, scopes []string, ownerID uint) (*models.ServiceClient, string, error) {
+	id, err := randomHex(12)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	secret = "ss_" + secret
+
+	client := models.ServiceClient{
+		ClientID:   ServiceClientIDPrefix + id,
+		SecretHash: HashToken(secret),
+		Name:       name,
+		Scopes:     strings.Join(scopes, " "),
+		OwnerID:    ownerID,
+	}
+	if err := config.DB.Create(&client).Error; err != nil {
+		return nil, "", err
+	}
+	return &client, secret, nil
+}
+
+// RotateServiceClientSecret remplace le secret d'un client de service et retourne le nouveau.
+// Les tokens déjà émis restent valides jusqu'à leur expiration.
+func RotateServiceClientSecret(client *models.ServiceClient) (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	secret = "ss_" + secret
+	if err := config.DB.Model(client).Update("secret_hash", HashToken(secret)).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RevokeServiceClient désactive un client de service ; ses tokens sont refusés immédiatement.
+func RevokeServiceClient(client *models.ServiceClient) error {
+	return config.DB.Model(client).Update("revoked_at", time.Now()).Error
+}
+
+// FindServiceClient charge un client de service actif par son client_id.
+func FindServiceClient(clientID string) (*models.ServiceClient, error) {
+	var client models.ServiceClient
+	if clientID == "" || config.DB.Where("client_id = ? AND revoked_at IS NULL", clientID).First(&client).Error != nil {
+		return nil, oauthError(OAuthInvalidClient, "client inconnu ou désactivé")
+	}
+	return &client, nil
+}
+
+// AuthenticateServiceClient vérifie les identifiants présentés par un service à /oauth/token.
+func AuthenticateServiceClient(clientID, secret string) (*models.ServiceClient, error) {
+	client, err := FindServiceClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError(OAuthInvalidClient, "authentification du client échouée")
+	}
+	return client, nil
+}
+
+// IssueServiceToken émet un token d'accès pour le compte d'un client de service, limité à
+// scope (tous ses scopes si vide).
+func IssueServiceToken(client *models.ServiceClient, requested string) (string, string, error) {
+	scope, err := resolveScope(strings.Fields(client.Scopes), requested)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := newClaims(ServiceSubjectPrefix+client.ClientID, TokenTypeAccess, NewTokenID(), time.Now().Add(ServiceTokenTTL))
+	claims.ClientID = client.ClientID
+	claims.Scopes = strings.Fields(scope)
+	token, err := SignToken(claims)
+	if err != nil {
+		return "", "", err
+	}
+
+	config.DB.Model(client).Update("last_used_at", time.Now())
+	return token, scope, nil
+}
+
+// IsServiceTokenRevoked indique si un token de service a été révoqué, ou si son client a
+// été désactivé depuis son émission.
+func IsServiceTokenRevoked(jti, clientID string) bool {
+	var count int64
+	if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil || count > 0 {
+		return true
+	}
+	_, err := FindServiceClient(clientID)
+	return err != nil
+}
@@ -0,0 +1,132 @@
+// utils/session.go
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// HashToken retourne l'empreinte SHA-256 d'un token, seule forme stockée en base.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession ouvre une nouvelle session et retourne son premier refresh token.
+// authMethods sont les méthodes d'authentification utilisées (valeurs "amr" de la RFC 8176).
+// L'organisation active est la plus ancienne de l'utilisateur (voir DefaultOrganizationID).
+func CreateSession(userID uint, deviceName string, authMethods []string, userAgent, ip string) (*models.Session, string, error) {
+	return openSession(models.Session{
+		UserID:      userID,
+		DeviceName:  deviceName,
+		UserAgent:   userAgent,
+		IP:          ip,
+		AuthMethods: strings.Join(authMethods, " "),
+	})
+}
+
+// CreateOAuthSession ouvre une session pour le compte d'un client OAuth, limitée aux scopes
+// accordés. authTime et authMethods décrivent l'authentification de l'utilisateur qui a
+// donné son accord.
+func CreateOAuthSession(userID uint, client *models.OAuthClient, scope string, authTime time.Time, authMethods, userAgent, ip string) (*models.Session, string, error) {
+	return openSession(models.Session{
+		UserID:      userID,
+		DeviceName:  client.Name,
+		UserAgent:   userAgent,
+		IP:          ip,
+		ClientID:    client.ClientID,
+		Scope:       scope,
+		AuthTime:    authTime,
+		AuthMethods: authMethods,
+	})
+}
+
+// openSession complète et enregistre une nouvelle session avec sa famille de refresh tokens.
+func openSession(session models.Session) (*models.Session, string, error) {
+	familyID := NewTokenID()
+	refreshToken, err := IssueRefreshToken(session.UserID, familyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session.FamilyID = familyID
+	session.OrganizationID = DefaultOrganizationID(session.UserID)
+	session.RefreshTokenHash = HashToken(refreshToken)
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(RefreshTokenTTL)
+	if session.AuthTime.IsZero() {
+		session.AuthTime = now
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, refreshToken, nil
+}
+
+// RefreshSession fait tourner le refresh token d'une session et met à jour son activité.
+// clientID doit être celui du client OAuth de la session (vide pour une connexion directe).
+func RefreshSession(refreshToken, clientID, userAgent, ip string) (*models.Session, string, error) {
+	claims, err := ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var session models.Session
+	if err := config.DB.Where("family_id = ?", claims.FamilyID).First(&session).Error; err != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) || session.ClientID != clientID {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	_, newToken, err := RotateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session.RefreshTokenHash = HashToken(newToken)
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(RefreshTokenTTL)
+	session.UserAgent = userAgent
+	session.IP = ip
+	if err := config.DB.Save(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, newToken, nil
+}
+
+// IsSessionActive indique si une session existe et n'a pas été fermée.
+func IsSessionActive(sessionID uint) bool {
+	var count int64
+	err := config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, time.Now()).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// RevokeSession ferme une session ainsi que sa famille de refresh tokens.
+func RevokeSession(session *models.Session) error {
+	return RevokeRefreshTokenFamily(session.FamilyID)
+}
+
+// SessionAuthTime retourne le moment où l'utilisateur s'est authentifié pour ouvrir la session
+// (sa création pour les sessions antérieures à l'enregistrement de cette date).
+func SessionAuthTime(session *models.Session) time.Time {
+	if session.AuthTime.IsZero() {
+		return session.CreatedAt
+	}
+	return session.AuthTime
+}
+
+// PurgeExpiredSessions supprime les sessions expirées.
+func PurgeExpiredSessions() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
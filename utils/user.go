@@ -0,0 +1,79 @@
+// utils/user.go
+
+package utils
+
+import (
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/gorm"
+)
+
+// SoftDeleteUser désactive un compte (deleted_at) sans rien supprimer : RestoreUser peut le
+// rétablir. Ses sessions et ses familles de refresh tokens sont révoquées, et les tokens
+// d'accès déjà émis refusés.
+func SoftDeleteUser(userID uint) error {
+	now := time.Now()
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NULL", userID).
+			Updates(map[string]interface{}{"deleted_at": now, "tokens_revoked_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// PurgeUser supprime définitivement un utilisateur, même désactivé, avec ses rôles, ses
+// appartenances aux organisations et aux groupes, ses clés d'API et ses sessions. Le journal
+// d'activité est conservé.
+func PurgeUser(userID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"DELETE FROM user_roles WHERE user_id = ?",
+			"DELETE FROM membership_roles WHERE membership_id IN (SELECT id FROM memberships WHERE user_id = ?)",
+			"DELETE FROM group_members WHERE user_id = ?",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement, userID).Error; err != nil {
+				return err
+			}
+		}
+		owned := []interface{}{
+			&models.Membership{},
+			&models.APIKey{},
+			&models.Session{},
+			&models.RefreshToken{},
+			&models.Passkey{},
+			&models.RecoveryCode{},
+			&models.PasswordResetToken{},
+			&models.OAuthConsent{},
+			&models.OAuthAuthorizationCode{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Unscoped().Delete(&models.User{}, userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
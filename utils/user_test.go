@@ -0,0 +1,110 @@
+// utils/user_test.go
+
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB ouvre une base SQLite en mémoire, clés étrangères actives comme sous PostgreSQL.
+func openTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?_foreign_keys=on"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&models.Permission{},
+		&models.Role{},
+		&models.User{},
+		&models.Group{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.ActivityLog{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.RecoveryCode{},
+		&models.Passkey{},
+		&models.PasswordResetToken{},
+		&models.APIKey{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthConsent{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+}
+
+func TestPurgeUserWithRole(t *testing.T) {
+	openTestDB(t)
+	db := config.DB
+
+	role := models.Role{Name: "support"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: "support", Roles: []models.Role{role}}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	org := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatal(err)
+	}
+	records := []interface{}{
+		&models.Membership{OrganizationID: org.ID, UserID: user.ID, Roles: []models.Role{role}},
+		&models.Group{Name: "support-tier-2", Members: []models.User{user}},
+		&models.APIKey{UserID: user.ID, Name: "ci", Prefix: "pat_test", KeyHash: "hash"},
+		&models.Session{UserID: user.ID, FamilyID: "family", RefreshTokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)},
+		&models.RefreshToken{UserID: user.ID, JTI: "jti", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sans nettoyage préalable, les clés étrangères refusent la suppression
+	if err := db.Delete(&models.User{}, user.ID).Error; err == nil {
+		t.Fatal("suppression directe acceptée : les clés étrangères ne sont pas vérifiées")
+	}
+
+	if err := PurgeUser(user.ID); err != nil {
+		t.Fatalf("PurgeUser: %v", err)
+	}
+
+	var count int64
+	db.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Error("l'utilisateur existe toujours")
+	}
+	for _, table := range []string{"user_roles", "memberships", "group_members", "api_keys", "sessions", "refresh_tokens"} {
+		db.Table(table).Where("user_id = ?", user.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("%s : %d ligne(s) restante(s)", table, count)
+		}
+	}
+	db.Table("membership_roles").Count(&count)
+	if count != 0 {
+		t.Errorf("membership_roles : %d ligne(s) restante(s)", count)
+	}
+	// Le rôle lui-même n'est pas touché
+	if err := db.First(&models.Role{}, role.ID).Error; err != nil {
+		t.Errorf("rôle supprimé : %v", err)
+	}
+
+	if err := PurgeUser(user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("PurgeUser d'un utilisateur inconnu : %v, attendu gorm.ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,180 @@
+// utils/webauthn.go
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/kdev1966/go-auth-api/config"
+	"github.com/kdev1966/go-auth-api/models"
+)
+
+// Cérémonies WebAuthn.
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// PasskeyChallengeTTL est la durée laissée pour terminer une cérémonie WebAuthn.
+const PasskeyChallengeTTL = 5 * time.Minute
+
+var ErrPasskeyChallenge = errors.New("challenge WebAuthn invalide ou expiré")
+
+var (
+	webAuthnOnce     sync.Once
+	webAuthnInstance *webauthn.WebAuthn
+	webAuthnErr      error
+)
+
+// WebAuthn retourne la configuration du Relying Party, lue depuis :
+//   - WEBAUTHN_RP_ID : domaine du service (ex: example.com)
+//   - WEBAUTHN_RP_NAME : nom affiché par le navigateur
+//   - WEBAUTHN_RP_ORIGINS : origines autorisées, séparées par des virgules
+func WebAuthn() (*webauthn.WebAuthn, error) {
+	webAuthnOnce.Do(func() {
+		name := os.Getenv("WEBAUTHN_RP_NAME")
+		if name == "" {
+			name = "Go Auth API"
+		}
+		webAuthnInstance, webAuthnErr = webauthn.New(&webauthn.Config{
+			RPID:          os.Getenv("WEBAUTHN_RP_ID"),
+			RPDisplayName: name,
+			RPOrigins:     strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ","),
+		})
+	})
+	return webAuthnInstance, webAuthnErr
+}
+
+// PasskeyUser adapte models.User à l'interface webauthn.User.
+type PasskeyUser struct {
+	User     *models.User
+	Passkeys []models.Passkey
+}
+
+// LoadPasskeyUser charge un utilisateur et ses passkeys.
+func LoadPasskeyUser(userID uint) (*PasskeyUser, error) {
+	var user models.User
+	if err := config.DB.Where("deleted_at IS NULL").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	var passkeys []models.Passkey
+	if err := config.DB.Where("user_id = ?", userID).Find(&passkeys).Error; err != nil {
+		return nil, err
+	}
+	return &PasskeyUser{User: &user, Passkeys: passkeys}, nil
+}
+
+// WebAuthnID est le "user handle" : l'ID de l'utilisateur, sans donnée personnelle.
+func (u *PasskeyUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.User.ID), 10))
+}
+
+func (u *PasskeyUser) WebAuthnName() string        { return u.User.Email }
+func (u *PasskeyUser) WebAuthnDisplayName() string { return u.User.Username }
+func (u *PasskeyUser) WebAuthnIcon() string        { return "" }
+
+func (u *PasskeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Passkeys))
+	for _, passkey := range u.Passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialID)
+		if err != nil {
+			continue
+		}
+		var transports []protocol.AuthenticatorTransport
+		if passkey.Transports != "" {
+			for _, t := range strings.Split(passkey.Transports, ",") {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// EncodeCredentialID encode un identifiant de credential tel que stocké en base (base64url).
+func EncodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// NewPasskey convertit un identifiant validé par la cérémonie d'enregistrement.
+func NewPasskey(userID uint, name string, credential *webauthn.Credential) models.Passkey {
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+	return models.Passkey{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    EncodeCredentialID(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
+
+// SavePasskeyChallenge conserve l'état d'une cérémonie jusqu'à l'étape finish.
+func SavePasskeyChallenge(ceremony string, userID uint, label string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	challenge := models.PasskeyChallenge{
+		Challenge:   session.Challenge,
+		UserID:      userID,
+		Ceremony:    ceremony,
+		Label:       label,
+		SessionData: string(data),
+		ExpiresAt:   time.Now().Add(PasskeyChallengeTTL),
+	}
+	return config.DB.Create(&challenge).Error
+}
+
+// ConsumePasskeyChallenge récupère et supprime l'état d'une cérémonie : un challenge ne sert qu'une fois.
+func ConsumePasskeyChallenge(ceremony, challenge string) (*models.PasskeyChallenge, *webauthn.SessionData, error) {
+	var record models.PasskeyChallenge
+	if err := config.DB.Where("challenge = ? AND ceremony = ?", challenge, ceremony).First(&record).Error; err != nil {
+		return nil, nil, ErrPasskeyChallenge
+	}
+	result := config.DB.Delete(&models.PasskeyChallenge{}, record.ID)
+	if result.Error != nil || result.RowsAffected == 0 || record.ExpiresAt.Before(time.Now()) {
+		return nil, nil, ErrPasskeyChallenge
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(record.SessionData), &session); err != nil {
+		return nil, nil, ErrPasskeyChallenge
+	}
+	return &record, &session, nil
+}
+
+// PurgeExpiredPasskeyChallenges supprime les cérémonies abandonnées.
+func PurgeExpiredPasskeyChallenges() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.PasskeyChallenge{})
+	return result.RowsAffected, result.Error
+}